@@ -0,0 +1,114 @@
+package apk
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const repositoriesFile = "/etc/apk/repositories"
+
+// SingBox 为 Alpine community 仓库中的 sing-box 包名。
+const SingBox = "sing-box"
+
+var pkgVersionRe = regexp.MustCompile(`-r[0-9]+$`)
+
+// Package 描述仓库中的一个包，Version 为 apk 版本（如 1.10.1-r0）。
+type Package struct {
+	Name    string
+	Version string
+}
+
+// String 返回 apk 惯用的 <name>-<version> 形式。
+func (p Package) String() string {
+	return p.Name + "-" + p.Version
+}
+
+// Upstream 返回去掉 -rN 修订号后的上游版本。
+func (p Package) Upstream() string {
+	return pkgVersionRe.ReplaceAllString(p.Version, "")
+}
+
+func IsInstalled(ctx context.Context, name string) bool {
+	return exec.CommandContext(ctx, "apk", "info", "-e", name).Run() == nil
+}
+
+// Add 安装（upgrade 为 true 时升级）指定包；每次刷新索引，避免使用过期的缓存。
+func Add(ctx context.Context, name string, upgrade bool) error {
+	if err := checkCommunity(); err != nil {
+		return err
+	}
+	args := []string{"add", "--no-cache"}
+	if upgrade {
+		args = append(args, "--upgrade")
+	}
+	return system.Run(ctx, "apk", append(args, name)...)
+}
+
+func Del(ctx context.Context, name string) error {
+	return system.Run(ctx, "apk", "del", name)
+}
+
+// Installed 返回已安装的包版本。
+func Installed(ctx context.Context, name string) (Package, error) {
+	out, err := system.Output(ctx, "apk", "info", "-d", name)
+	if err != nil {
+		return Package{}, err
+	}
+	// 输出首行形如 "sing-box-1.10.1-r0 description:"。
+	first, _, _ := strings.Cut(strings.TrimSpace(out), " ")
+	v, ok := strings.CutPrefix(first, name+"-")
+	if !ok || !pkgVersionRe.MatchString(v) {
+		return Package{}, fmt.Errorf("无法解析 apk info 输出: %q", first)
+	}
+	return Package{Name: name, Version: v}, nil
+}
+
+// Available 返回仓库中可安装的最新版本。
+func Available(ctx context.Context, name string) (Package, error) {
+	out, err := system.Output(ctx, "apk", "search", "--no-cache", "-x", name)
+	if err != nil {
+		return Package{}, err
+	}
+	for _, line := range strings.Fields(out) {
+		v, ok := strings.CutPrefix(line, name+"-")
+		if ok && pkgVersionRe.MatchString(v) {
+			return Package{Name: name, Version: v}, nil
+		}
+	}
+	return Package{}, fmt.Errorf("apk 仓库中未找到 %s，请确认已启用 community 仓库", name)
+}
+
+// checkCommunity 确认 /etc/apk/repositories 中启用了 community 仓库（sing-box 不在 main 仓库）。
+func checkCommunity() error {
+	f, err := os.Open(repositoriesFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, "/community") {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("未启用 community 仓库：请在 %s 中取消 community 行的注释后重试", repositoriesFile)
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/apk"
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+var errAPKOptions = errors.New("--source apk 由 apk 仓库决定版本，不支持 --version/--from-archive/--bundle/--checksum-file/--channel")
+
+// installSource 返回生效的安装来源：显式指定优先，其次 state.json 中记录的来源（默认 github）。
+func (a *App) installSource(explicit string) (string, error) {
+	switch explicit {
+	case paths.SourceAPK:
+		if !system.IsAlpine() {
+			return "", errors.New("--source apk 仅支持 Alpine Linux")
+		}
+		return explicit, nil
+	case paths.SourceGitHub:
+		return explicit, nil
+	case "":
+	default:
+		return "", fmt.Errorf("未知的安装来源 %q：可选 %s 或 %s", explicit, paths.SourceGitHub, paths.SourceAPK)
+	}
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return "", err
+	}
+	if st.Source != "" {
+		return st.Source, nil
+	}
+	return paths.SourceGitHub, nil
+}
+
+func checkAPKOptions(opts InstallOptions) error {
+	if opts.Version != "" || opts.ChecksumFile != "" || opts.Channel != "" || opts.offline() {
+		return errAPKOptions
+	}
+	return nil
+}
+
+// installSingBoxAPK 通过 apk 安装 sing-box 包；added 表示包由本次调用新装（而非已存在）。
+func (a *App) installSingBoxAPK(ctx context.Context, opts InstallOptions) (bin state.Binary, added bool, err error) {
+	if err := checkAPKOptions(opts); err != nil {
+		return state.Binary{}, false, err
+	}
+
+	added = !apk.IsInstalled(ctx, apk.SingBox)
+	if added {
+		fmt.Fprintln(a.Out, "通过 apk 安装 sing-box（community 仓库）...")
+		if err := apk.Add(ctx, apk.SingBox, false); err != nil {
+			return state.Binary{}, false, err
+		}
+	} else {
+		fmt.Fprintln(a.Out, "已通过 apk 安装 sing-box 包，直接使用。")
+	}
+
+	bin, err = a.apkBinaryState(ctx)
+	return bin, added, err
+}
+
+func (a *App) apkBinaryState(ctx context.Context) (state.Binary, error) {
+	pkg, err := apk.Installed(ctx, apk.SingBox)
+	if err != nil {
+		return state.Binary{}, err
+	}
+	info, err := singbox.BinaryVersion(ctx, a.Paths.SingBoxPath)
+	if err != nil {
+		return state.Binary{}, err
+	}
+	fmt.Fprintf(a.Out, "sing-box 自检通过：版本 %s（apk 包 %s），构建标签 %s\n", info.Version, pkg, strings.Join(info.Tags, ","))
+
+	// 包签名由 apk 校验，这里不再记录压缩包摘要。
+	return state.Binary{
+		Version:     info.Version,
+		Asset:       pkg.String(),
+		Tags:        info.Tags,
+		Verified:    true,
+		InstalledAt: time.Now().UTC(),
+	}, nil
+}
+
+// cleanupPreviousSource 在切换安装来源后清理旧来源留下的 sing-box，保持卸载对称。
+func (a *App) cleanupPreviousSource(ctx context.Context, prev state.State, source string) error {
+	prevSource := prev.Source
+	if prevSource == "" {
+		prevSource = paths.SourceGitHub
+	}
+	if prevSource == source {
+		return nil
+	}
+
+	switch prevSource {
+	case paths.SourceAPK:
+		if !prev.PackageOwned {
+			return nil
+		}
+		fmt.Fprintln(a.Out, "已切换到 GitHub 安装包，删除此前由本工具安装的 apk sing-box 包。")
+		return apk.Del(ctx, apk.SingBox)
+	default:
+		local := a.Paths.ForSource(paths.SourceGitHub)
+		_ = system.RemoveAll(local.SingBoxPath)
+		_ = system.RemoveAll(local.SingBoxPrevPath)
+		return nil
+	}
+}
+
+// upgradeAPK 通过 apk 升级 sing-box。Alpine 仓库通常只保留最新版本、无法用 apk 降级，
+// 因此升级前把当前二进制复制为 sing-box.prev，回滚时用它覆盖 /usr/bin/sing-box。
+// /usr/bin/sing-box 由所有 apk 来源的实例共用，这些实例的配置要一起迁移、校验，并一起重启与回滚。
+func (a *App) upgradeAPK(ctx context.Context, opts InstallOptions, port int) error {
+	if err := checkAPKOptions(opts); err != nil {
+		return err
+	}
+
+	cur, err := singbox.BinaryVersion(ctx, a.Paths.SingBoxPath)
+	if err != nil {
+		return err
+	}
+	pkg, err := apk.Available(ctx, apk.SingBox)
+	if err != nil {
+		return err
+	}
+	if singbox.CompareVersions(pkg.Upstream(), cur.Version) <= 0 {
+		fmt.Fprintf(a.Out, "sing-box 已是 apk 仓库中的最新版本 %s，无需升级。\n", cur.Version)
+		return nil
+	}
+
+	group, err := a.apkPeers(ctx)
+	if err != nil {
+		return err
+	}
+	group = append([]apkMember{{app: a, port: port}}, group...)
+	if len(group) > 1 {
+		fmt.Fprintf(a.Out, "另有 %d 个实例共用 apk 安装的 sing-box，将一并迁移配置并重启。\n", len(group)-1)
+	}
+	defer func() {
+		for _, m := range group {
+			_ = os.Remove(m.app.Paths.ConfigPath + ".new")
+		}
+	}()
+
+	if err := system.CopyFile(a.Paths.SingBoxPath, a.Paths.SingBoxPrevPath, 0755); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "通过 apk 升级 sing-box：%s -> %s\n", cur.Version, pkg.Upstream())
+	if err := apk.Add(ctx, apk.SingBox, true); err != nil {
+		return err
+	}
+
+	bin, err := a.apkBinaryState(ctx)
+	if err != nil {
+		return errors.Join(err, a.restoreBinary())
+	}
+
+	for i := range group {
+		m := &group[i]
+		var checkPath string
+		m.raw, checkPath, m.changed, err = m.app.stageConfig(bin.Version)
+		if err == nil {
+			err = singbox.CheckConfig(ctx, a.Paths.SingBoxPath, checkPath)
+		}
+		if err != nil {
+			if rbErr := a.restoreBinary(); rbErr != nil {
+				return fmt.Errorf("新版本 sing-box 校验 %s 的配置失败: %v；恢复旧版本也失败: %w", m.app.Paths.ServiceName, err, rbErr)
+			}
+			return fmt.Errorf("新版本 sing-box 校验 %s 的配置失败，已恢复旧版本二进制（%s）: %w", m.app.Paths.ServiceName, apkDriftNote, err)
+		}
+	}
+	for i := range group {
+		m := &group[i]
+		if !m.changed {
+			continue
+		}
+		if err := m.app.commitConfig(m.raw); err != nil {
+			return errors.Join(err, a.rollbackAPK(ctx, group[:i]))
+		}
+	}
+
+	for _, m := range group {
+		if err := m.app.restartHealthy(ctx, m.port); err != nil {
+			err = fmt.Errorf("%s: %w", m.app.Paths.ServiceName, err)
+			if rbErr := a.rollbackAPK(ctx, group); rbErr != nil {
+				return fmt.Errorf("升级失败: %v；回滚也失败: %w", err, rbErr)
+			}
+			return fmt.Errorf("升级失败，已自动回滚到旧版本（%s）: %w", apkDriftNote, err)
+		}
+	}
+
+	for _, m := range group {
+		if err := m.app.saveBinaryState(bin, ""); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(a.Out, "已通过 apk 升级 sing-box 到 %s，旧版本保留为 %s。\n", bin.Version, a.Paths.SingBoxPrevPath)
+	return nil
+}
+
+// apkMember 为共用 apk 安装的 sing-box 的一个实例及其升级过程中的配置状态。
+type apkMember struct {
+	app     *App
+	port    int
+	raw     []byte
+	changed bool
+}
+
+// apkPeers 列出本机其他以 apk 为安装来源的实例。
+func (a *App) apkPeers(ctx context.Context) ([]apkMember, error) {
+	list, err := a.initSys.Instances()
+	if err != nil {
+		return nil, err
+	}
+	var peers []apkMember
+	for _, inst := range list {
+		if inst.ServiceName == a.Paths.ServiceName || inst.Home == "" {
+			continue
+		}
+		p := paths.At(inst.Home, inst.Name).ForSource(paths.SourceAPK)
+		st, err := state.Load(p.StatePath)
+		if err != nil {
+			return nil, err
+		}
+		if st.Source != paths.SourceAPK {
+			continue
+		}
+		cfg, err := singbox.ReadConfig(p.ConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取实例 %s 的配置失败: %w", inst.ServiceName, err)
+		}
+		peer := *a
+		peer.Paths = p
+		peers = append(peers, apkMember{app: &peer, port: cfg.Node.Port})
+	}
+	return peers, nil
+}
+
+// rollbackAPK 恢复旧版本二进制与 members 中已迁移的配置，并重启这些实例。
+func (a *App) rollbackAPK(ctx context.Context, members []apkMember) error {
+	if err := a.restoreBinary(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, m := range members {
+		if m.changed {
+			if err := os.Rename(m.app.Paths.ConfigPrevPath, m.app.Paths.ConfigPath); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		errs = append(errs, m.app.restartService(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+const apkDriftNote = "apk 数据库仍记录新版本，下次 apk upgrade 会再次覆盖"
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/apk"
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/schedule"
+	"github.com/pkssssss/alpine-vless/internal/selfupdate"
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+)
+
+const (
+	autoUpdateJobID     = "auto-update"
+	defaultUpdateWindow = "03:00-05:00"
+)
+
+type window struct {
+	start, end int // 当天的分钟数
+}
+
+func parseWindow(s string) (window, error) {
+	a, b, ok := strings.Cut(s, "-")
+	if !ok {
+		return window{}, fmt.Errorf("非法的维护窗口 %q：格式应为 HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(a)
+	if err != nil {
+		return window{}, err
+	}
+	end, err := parseClock(b)
+	if err != nil {
+		return window{}, err
+	}
+	if start == end {
+		return window{}, fmt.Errorf("非法的维护窗口 %q：起止时间相同", s)
+	}
+	return window{start, end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("非法的时间 %q：格式应为 HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w window) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+func (w window) cronSpec() string {
+	return fmt.Sprintf("%d %d * * *", w.start%60, w.start/60)
+}
+
+func (a *App) EnableAutoUpdate(ctx context.Context, win, channel string) error {
+	if win == "" {
+		win = defaultUpdateWindow
+	}
+	w, err := parseWindow(win)
+	if err != nil {
+		return err
+	}
+	if channel != "" {
+		if err := singbox.ValidateChannel(channel); err != nil {
+			return err
+		}
+	}
+
+	cmd, err := a.selfCommand("auto-update", "run")
+	if err != nil {
+		return err
+	}
+	if err := schedule.Install(ctx, a.jobID(autoUpdateJobID), w.cronSpec(), cmd); err != nil {
+		return err
+	}
+
+	if err := a.updateState(func(st *state.State) {
+		st.AutoUpdate = state.AutoUpdate{Enabled: true, Window: win}
+		if channel != "" {
+			st.Channel = channel
+		}
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Out, "已开启自动更新：维护窗口 %s（每日 %02d:%02d 检查），记录见 %s\n",
+		win, w.start/60, w.start%60, a.Paths.UpdateLogPath)
+	return nil
+}
+
+func (a *App) DisableAutoUpdate() error {
+	if err := schedule.Remove(a.jobID(autoUpdateJobID)); err != nil {
+		return err
+	}
+	if err := a.updateState(func(st *state.State) { st.AutoUpdate.Enabled = false }); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.Out, "已关闭自动更新。")
+	return nil
+}
+
+// RunAutoUpdate 由定时任务调用：在维护窗口内检查通道上的新版本并升级（升级自带健康检查与回滚）。
+func (a *App) RunAutoUpdate(ctx context.Context) error {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	if !st.AutoUpdate.Enabled {
+		return nil
+	}
+
+	w, err := parseWindow(st.AutoUpdate.Window)
+	if err != nil {
+		return a.logUpdate("error", err.Error())
+	}
+	if !w.contains(time.Now()) {
+		return a.logUpdate("skipped", "不在维护窗口 "+st.AutoUpdate.Window+" 内")
+	}
+	if !a.IsInstalled() {
+		return a.logUpdate("skipped", "未检测到本工具管理的已部署实例")
+	}
+
+	cur, err := singbox.BinaryVersion(ctx, a.Paths.SingBoxPath)
+	if err != nil {
+		return a.logUpdate("error", err.Error())
+	}
+	channel, latest, err := a.latestVersion(ctx, st)
+	if err != nil {
+		return a.logUpdate("error", "获取最新版本失败: "+err.Error())
+	}
+	if singbox.CompareVersions(latest, cur.Version) <= 0 {
+		return a.logUpdate("up-to-date", fmt.Sprintf("channel=%s version=%s", channel, cur.Version))
+	}
+
+	opts := InstallOptions{Version: latest}
+	if st.Source == paths.SourceAPK {
+		opts = InstallOptions{}
+	}
+	if err := a.UpgradeWith(ctx, opts); err != nil {
+		return errors.Join(a.logUpdate("failed", fmt.Sprintf("%s -> %s: %v", cur.Version, latest, err)), err)
+	}
+	return a.logUpdate("upgraded", fmt.Sprintf("%s -> %s", cur.Version, latest))
+}
+
+// latestVersion 返回安装来源上的最新版本及其通道名：apk 安装以 apk 仓库为准，其余按更新通道查询 GitHub。
+func (a *App) latestVersion(ctx context.Context, st state.State) (channel, version string, err error) {
+	if st.Source == paths.SourceAPK {
+		pkg, err := apk.Available(ctx, apk.SingBox)
+		if err != nil {
+			return paths.SourceAPK, "", err
+		}
+		return paths.SourceAPK, pkg.Upstream(), nil
+	}
+
+	channel, err = a.channel("")
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := singbox.ResolveLatest(ctx, a.httpClient, a.source, channel, a.versionCachePath(channel))
+	if err != nil {
+		return channel, "", err
+	}
+	return channel, rel.Version, nil
+}
+
+func (a *App) logUpdate(result, detail string) error {
+	f, err := os.OpenFile(a.Paths.UpdateLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	detail = strings.ReplaceAll(detail, "\n", " ")
+	_, err = fmt.Fprintf(f, "%s result=%s %s\n", time.Now().Format(time.RFC3339), result, detail)
+	return err
+}
+
+// jobID 为定时任务加上服务名前缀，便于在 crontab 中识别与清理本工具的任务。
+func (a *App) jobID(name string) string {
+	return a.Paths.ServiceName + ":" + name
+}
+
+// selfCommand 生成定时任务中调用本工具的命令行，固定数据目录与实例，输出丢弃。
+func (a *App) selfCommand(args ...string) (string, error) {
+	exe, err := selfupdate.Executable()
+	if err != nil {
+		return "", err
+	}
+	cmd := fmt.Sprintf("ALPINE_VLESS_HOME=%s %s", schedule.ShellQuote(a.Paths.RootDir), schedule.ShellQuote(exe))
+	if a.Paths.Instance != "" {
+		cmd += " --instance " + a.Paths.Instance
+	}
+	return cmd + " " + strings.Join(args, " ") + " >/dev/null 2>&1", nil
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/bundle"
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+type BundleOptions struct {
+	Versions []string
+	Archs    []string
+	Libc     string
+	Output   string
+}
+
+// Bundle 在联网机器上下载指定版本/架构的 sing-box 压缩包，连同摘要打成一个离线包。
+func (a *App) Bundle(ctx context.Context, opts BundleOptions) error {
+	if len(opts.Archs) == 0 {
+		return errors.New("请通过 --arch 指定至少一个架构")
+	}
+	if len(opts.Versions) == 0 {
+		opts.Versions = []string{""}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "alpine-vless-bundle-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	var files []bundle.File
+	for _, v := range opts.Versions {
+		var rel singbox.Release
+		if v == "" {
+			rel, err = singbox.LatestRelease(ctx, a.httpClient, a.source)
+		} else {
+			rel, err = singbox.FetchRelease(ctx, a.httpClient, a.source, v)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, arch := range opts.Archs {
+			asset, err := singbox.SelectAsset(rel, singbox.Platform{OS: "linux", Archs: []string{arch}, Libc: opts.Libc})
+			if err != nil {
+				return err
+			}
+			if asset.SHA256 == "" {
+				return fmt.Errorf("release 未提供 %s 的摘要，无法生成可校验的离线包", asset.Name)
+			}
+
+			fmt.Fprintf(a.Out, "下载 %s（%s）\n", asset.Name, system.FormatBytes(asset.Size))
+			path, sum, err := singbox.Download(ctx, a.httpClient, singbox.InstallSpec{
+				Source:      a.source,
+				Version:     rel.Version,
+				Asset:       asset.Name,
+				SHA256:      asset.SHA256,
+				Size:        asset.Size,
+				DownloadDir: tmpDir,
+				Progress:    a.Out,
+			})
+			if err != nil {
+				return err
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+
+			files = append(files, bundle.File{
+				Entry: bundle.Entry{
+					Version: rel.Version,
+					Asset:   asset.Name,
+					SHA256:  sum,
+					Size:    info.Size(),
+				},
+				Path: path,
+			})
+		}
+	}
+
+	if err := bundle.Write(opts.Output, files); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "已生成离线包 %s（%d 个安装包）。\n", opts.Output, len(files))
+	return nil
+}
+
+func (a *App) installSingBoxBundle(ctx context.Context, opts InstallOptions, plat singbox.Platform, destPath string) (state.Binary, error) {
+	m, err := bundle.ReadManifest(opts.Bundle)
+	if err != nil {
+		return state.Binary{}, err
+	}
+
+	byVersion := map[string][]singbox.Asset{}
+	for _, e := range m.Entries {
+		byVersion[e.Version] = append(byVersion[e.Version], singbox.Asset{Name: e.Asset, Size: e.Size, SHA256: e.SHA256})
+	}
+
+	var rel singbox.Release
+	var asset singbox.Asset
+	for v, assets := range byVersion {
+		if opts.Version != "" && v != opts.Version {
+			continue
+		}
+		if rel.Version != "" && singbox.CompareVersions(v, rel.Version) <= 0 {
+			continue
+		}
+		cand := singbox.Release{Version: v, Assets: assets}
+		if as, err := singbox.SelectAsset(cand, plat); err == nil {
+			rel, asset = cand, as
+		}
+	}
+	if rel.Version == "" {
+		want := "任意版本"
+		if opts.Version != "" {
+			want = opts.Version
+		}
+		return state.Binary{}, fmt.Errorf("离线包中没有适用于本机（%s，%s）的 sing-box（%s）", strings.Join(plat.Archs, "/"), plat.Libc, want)
+	}
+	fmt.Fprintf(a.Out, "从离线包安装: %s\n", asset.Name)
+
+	if err := os.MkdirAll(a.Paths.CacheDir, 0700); err != nil {
+		return state.Binary{}, err
+	}
+	tmp := filepath.Join(a.Paths.CacheDir, asset.Name+".bundle")
+	defer func() { _ = os.Remove(tmp) }()
+	if err := bundle.Extract(opts.Bundle, asset.Name, tmp); err != nil {
+		return state.Binary{}, err
+	}
+
+	inst, err := singbox.InstallArchive(ctx, tmp, singbox.InstallSpec{
+		Version:  rel.Version,
+		Asset:    asset.Name,
+		DestPath: destPath,
+		SHA256:   asset.SHA256,
+	})
+	if err != nil {
+		return state.Binary{}, err
+	}
+	return newBinaryState(rel.Version, asset.Name, inst, true), nil
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+// foregroundStopTimeout 为停止时等待 sing-box 退出的时间，超时后强制结束。
+const foregroundStopTimeout = 10 * time.Second
+
+type ForegroundOptions struct {
+	Install InstallOptions
+	// Port 非 0 时以该端口监听（已有配置时只更新端口）。
+	Port int
+}
+
+// Foreground 在前台运行 sing-box，不依赖 init 系统（容器入口）：首次运行时安装 sing-box 并生成配置，
+// 之后监督 sing-box 进程，崩溃后按 respawn 参数重启；sing-box 的日志与本工具的输出都写到 stdout。
+func (a *App) Foreground(ctx context.Context, opts ForegroundOptions) error {
+	if opts.Port < 0 || opts.Port > 65535 {
+		return fmt.Errorf("非法的端口 %d", opts.Port)
+	}
+	if err := system.MkdirAll0700(a.Paths.RootDir); err != nil {
+		return err
+	}
+
+	// 已安装时不再访问网络检查新版本，容器重启不依赖 GitHub；升级通过 run --version <版本> 重新启动完成
+	// （upgrade 依赖 init 系统重启服务，前台模式下不可用）。
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	version := st.SingBox.Version
+	if !system.FileExists(a.Paths.SingBoxPath) || opts.Install.Version != "" {
+		_, bin, _, err := a.ensureSingBox(ctx, opts.Install)
+		if err != nil {
+			return err
+		}
+		version = bin.Version
+		if err := a.migrateForegroundConfig(version); err != nil {
+			return err
+		}
+	}
+
+	if err := a.prepareForegroundConfig(ctx, opts.Port, version); err != nil {
+		return err
+	}
+	if err := a.writeRunConfig(); err != nil {
+		return err
+	}
+	if err := singbox.CheckConfig(ctx, a.Paths.SingBoxPath, a.Paths.RunConfigPath); err != nil {
+		return err
+	}
+	if !opts.Install.offline() {
+		if err := a.Show(ctx); err != nil {
+			return err
+		}
+	}
+
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	if svc.Limits, err = effectiveLimits(svc.Limits); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "资源限制: %s\n", formatLimits(svc.Limits))
+	if err := system.SetNoFile(uint64(svc.Limits.NoFile)); err != nil {
+		fmt.Fprintf(a.Out, "警告：设置最大打开文件数失败: %v\n", err)
+	}
+	return a.supervise(ctx, svc)
+}
+
+// migrateForegroundConfig 在更换 sing-box 版本后按新版本迁移已有配置，旧配置保留为 config.json.prev。
+func (a *App) migrateForegroundConfig(version string) error {
+	if !system.FileExists(a.Paths.ConfigPath) {
+		return nil
+	}
+	defer func() { _ = os.Remove(a.Paths.ConfigPath + ".new") }()
+	raw, _, changed, err := a.stageConfig(version)
+	if err != nil || !changed {
+		return err
+	}
+	return a.commitConfig(raw)
+}
+
+// prepareForegroundConfig 在没有配置时生成配置；指定了端口且与现有配置不同时保留凭据、只更换端口。
+func (a *App) prepareForegroundConfig(ctx context.Context, port int, version string) error {
+	if !system.FileExists(a.Paths.ConfigPath) {
+		node, err := singbox.NewDefaultNode(ctx)
+		if err != nil {
+			return err
+		}
+		if port != 0 {
+			node.Port = port
+		}
+		fmt.Fprintf(a.Out, "未检测到配置，已生成新配置（端口 %d）。\n", node.Port)
+		return singbox.WriteConfig(a.Paths.ConfigPath, a.Paths.LogPath, node, version)
+	}
+
+	cfg, err := singbox.ReadConfig(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if port == 0 || port == cfg.Node.Port {
+		return nil
+	}
+	node := cfg.Node
+	node.Port = port
+	fmt.Fprintf(a.Out, "监听端口由 %d 改为 %d。\n", cfg.Node.Port, port)
+	return singbox.WriteConfig(a.Paths.ConfigPath, a.Paths.LogPath, node, version)
+}
+
+// writeRunConfig 由 config.json 生成前台模式使用的配置（日志输出到终端）。
+func (a *App) writeRunConfig() error {
+	raw, err := os.ReadFile(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+	b, err := singbox.WithConsoleLog(raw)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.Paths.RunConfigPath, b, 0600)
+}
+
+// supervise 运行 sing-box 直到 ctx 取消（收到 SIGINT/SIGTERM）：SIGHUP 会在重新生成配置后转发给 sing-box；
+// sing-box 退出后等待 RespawnDelay 秒重启，RespawnPeriod 秒内退出超过 RespawnMax 次时放弃并返回错误。
+func (a *App) supervise(ctx context.Context, svc state.Service) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var crashes []time.Time
+	for {
+		stopped, err := a.runOnce(ctx, hup, svc.Limits)
+		if stopped {
+			return nil
+		}
+
+		if svc.RespawnMax > 0 {
+			now := time.Now()
+			crashes = append(crashes, now)
+			cutoff := now.Add(-time.Duration(svc.RespawnPeriod) * time.Second)
+			for len(crashes) > 0 && crashes[0].Before(cutoff) {
+				crashes = crashes[1:]
+			}
+			if len(crashes) > svc.RespawnMax {
+				return fmt.Errorf("sing-box 在 %d 秒内退出超过 %d 次，停止重启: %w", svc.RespawnPeriod, svc.RespawnMax, err)
+			}
+		}
+		fmt.Fprintf(a.Out, "sing-box 异常退出（%v），%d 秒后重启。\n", err, svc.RespawnDelay)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Duration(svc.RespawnDelay) * time.Second):
+		}
+	}
+}
+
+// runOnce 启动一次 sing-box 并等待其退出；stopped 表示因 ctx 取消而正常停止。
+// limits 中的 GOMEMLIMIT/GOGC 通过环境变量传给 sing-box，oom_score_adj 在启动后写入。
+func (a *App) runOnce(ctx context.Context, hup <-chan os.Signal, limits state.Limits) (stopped bool, err error) {
+	cmd := exec.Command(a.Paths.SingBoxPath, "run", "-c", a.Paths.RunConfigPath)
+	cmd.Stdout = a.Out
+	cmd.Stderr = a.Out
+	cmd.Env = os.Environ()
+	if limits.MemLimit != "" {
+		cmd.Env = append(cmd.Env, "GOMEMLIMIT="+limits.MemLimit)
+	}
+	if limits.GOGC != "" {
+		cmd.Env = append(cmd.Env, "GOGC="+limits.GOGC)
+	}
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+	fmt.Fprintf(a.Out, "sing-box 已启动（PID %d）。\n", cmd.Process.Pid)
+	if limits.OOMScoreAdj != "" {
+		if err := system.SetOOMScoreAdj(cmd.Process.Pid, limits.OOMScoreAdj); err != nil {
+			fmt.Fprintf(a.Out, "警告：设置 oom_score_adj 失败: %v\n", err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	for {
+		select {
+		case <-hup:
+			if err := a.writeRunConfig(); err != nil {
+				fmt.Fprintf(a.Out, "重新生成配置失败，未通知 sing-box: %v\n", err)
+				continue
+			}
+			_ = cmd.Process.Signal(syscall.SIGHUP)
+			fmt.Fprintln(a.Out, "已通知 sing-box 重新加载配置。")
+		case <-ctx.Done():
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+			select {
+			case <-done:
+			case <-time.After(foregroundStopTimeout):
+				_ = cmd.Process.Kill()
+				<-done
+			}
+			fmt.Fprintln(a.Out, "sing-box 已停止。")
+			return true, nil
+		case err := <-done:
+			if err == nil {
+				err = errors.New("退出码 0")
+			}
+			return false, err
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+func newHTTPClient() *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = proxyFromEnvironment
+
+	return &http.Client{
+		Transport: tr,
+		Timeout:   30 * time.Second,
+	}
+}
+
+// proxyFromEnvironment 在 HTTP(S)_PROXY/NO_PROXY 之外，额外支持 ALL_PROXY（如 socks5://127.0.0.1:1080）。
+func proxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	u, err := http.ProxyFromEnvironment(req)
+	if err != nil || u != nil {
+		return u, err
+	}
+
+	v := strings.TrimSpace(os.Getenv("ALL_PROXY"))
+	if v == "" {
+		v = strings.TrimSpace(os.Getenv("all_proxy"))
+	}
+	if v == "" || bypassProxy(req.URL.Hostname()) {
+		return nil, nil
+	}
+	return url.Parse(v)
+}
+
+func bypassProxy(host string) bool {
+	v := os.Getenv("NO_PROXY")
+	if v == "" {
+		v = os.Getenv("no_proxy")
+	}
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if p == "*" || host == p || strings.HasSuffix(host, "."+strings.TrimPrefix(p, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/pkssssss/alpine-vless/internal/initsys"
+	"github.com/pkssssss/alpine-vless/internal/openrc"
+	"github.com/pkssssss/alpine-vless/internal/system"
+	"github.com/pkssssss/alpine-vless/internal/systemd"
+)
+
+// detectInitSystem 选择服务管理后端：systemd 作为 init 运行时使用 systemd，否则要求 OpenRC。
+func detectInitSystem() (initsys.System, error) {
+	if systemd.Available() {
+		return systemd.System{}, nil
+	}
+	if system.CommandExists("rc-service") && system.CommandExists("rc-update") {
+		return openrc.System{}, nil
+	}
+	return nil, errors.New("未检测到受支持的 init 系统（需要 OpenRC 或 systemd）")
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const archiveCacheKeep = 3
+
+// errAlreadyInstalled 表示目标版本与已安装的 sing-box 一致，无需下载。
+var errAlreadyInstalled = errors.New("sing-box 已是目标版本")
+
+type InstallOptions struct {
+	Version      string
+	ChecksumFile string
+	Archive      string
+	Bundle       string
+	// Channel 为空时沿用 state.json 中记录的通道（默认 stable）。
+	Channel string
+	// Source 为安装来源（github/apk），为空时沿用 state.json 中记录的来源。
+	Source string
+	// InsecureSkipVerify 允许在没有可用摘要时不校验直接安装。
+	InsecureSkipVerify bool
+
+	// keepInstalled 允许在获取最新版本失败时沿用已安装的 sing-box，仅用于部署。
+	keepInstalled bool
+}
+
+// errNoChecksum 表示既没有 release 摘要也没有校验文件，默认拒绝安装。
+var errNoChecksum = errors.New("请通过 --checksum-file 指定校验文件，或使用 --insecure-skip-verify 跳过校验（不安全）")
+
+// offline 表示安装来源为本地文件，全程不访问网络。
+func (o InstallOptions) offline() bool {
+	return o.Archive != "" || o.Bundle != ""
+}
+
+func (a *App) installSingBox(ctx context.Context, opts InstallOptions, destPath string) (state.Binary, error) {
+	plat, err := singbox.DetectPlatform(runtime.GOARCH)
+	if err != nil {
+		return state.Binary{}, err
+	}
+
+	if opts.Archive != "" {
+		return a.installSingBoxArchive(ctx, opts, plat, destPath)
+	}
+	if opts.Bundle != "" {
+		return a.installSingBoxBundle(ctx, opts, plat, destPath)
+	}
+
+	var rel singbox.Release
+	var relErr error
+	version := opts.Version
+	if version == "" {
+		channel, err := a.channel(opts.Channel)
+		if err != nil {
+			return state.Binary{}, err
+		}
+		rel, err = singbox.ResolveLatest(ctx, a.httpClient, a.source, channel, a.versionCachePath(channel))
+		if err != nil {
+			if !opts.keepInstalled {
+				return state.Binary{}, err
+			}
+			cur, verr := singbox.BinaryVersion(ctx, a.Paths.SingBoxPath)
+			if verr != nil {
+				return state.Binary{}, err
+			}
+			fmt.Fprintf(a.Err, "警告：获取最新版本失败，沿用已安装的 %s：%v\n", cur.Version, err)
+			rel = singbox.Release{Version: cur.Version}
+		}
+		version = rel.Version
+	}
+
+	if cur, err := singbox.BinaryVersion(ctx, a.Paths.SingBoxPath); err == nil && cur.Version == version {
+		return state.Binary{Version: version}, errAlreadyInstalled
+	}
+
+	cache := a.archiveCache()
+	if opts.ChecksumFile == "" {
+		if cached, path, ok := cache.Lookup(version, plat); ok {
+			fmt.Fprintf(a.Out, "使用缓存的安装包: %s\n", cached.Name)
+			inst, err := singbox.InstallArchive(ctx, path, singbox.InstallSpec{
+				Version:  version,
+				Asset:    cached.Name,
+				DestPath: destPath,
+				SHA256:   cached.SHA256,
+			})
+			if err != nil {
+				return state.Binary{}, err
+			}
+			return newBinaryState(version, cached.Name, inst, true), nil
+		}
+	}
+
+	if len(rel.Assets) == 0 {
+		rel, relErr = singbox.FetchRelease(ctx, a.httpClient, a.source, version)
+		rel.Version = version
+	}
+
+	var asset singbox.Asset
+	if len(rel.Assets) > 0 {
+		asset, err = singbox.SelectAsset(rel, plat)
+		if err != nil {
+			return state.Binary{}, err
+		}
+		fmt.Fprintf(a.Out, "选择安装包: %s（%s）\n", asset.Name, system.FormatBytes(asset.Size))
+	} else {
+		// 无法确认 asset 是否存在时，选择兼容性最好的架构名（列表末尾）。
+		asset = singbox.Asset{Name: singbox.AssetName(rel.Version, plat.Archs[len(plat.Archs)-1])}
+		fmt.Fprintf(a.Out, "未获取到 release asset 列表，按默认命名下载: %s\n", asset.Name)
+	}
+
+	want, err := a.expectedSHA256(opts, asset, relErr)
+	if err != nil {
+		return state.Binary{}, err
+	}
+
+	inst, err := singbox.Install(ctx, a.httpClient, singbox.InstallSpec{
+		Source:   a.source,
+		Version:  rel.Version,
+		Asset:    asset.Name,
+		DestPath: destPath,
+		SHA256:   want,
+		Size:     asset.Size,
+
+		DownloadDir: a.Paths.CacheDir,
+		Progress:    a.Out,
+		Cache:       &cache,
+	})
+	if err != nil {
+		return state.Binary{}, err
+	}
+	if want != "" {
+		fmt.Fprintf(a.Out, "已校验 %s（SHA-256 %s）。\n", asset.Name, inst.SHA256)
+	}
+
+	return newBinaryState(version, asset.Name, inst, want != ""), nil
+}
+
+func newBinaryState(version, asset string, inst singbox.Installed, verified bool) state.Binary {
+	_, arch, _ := singbox.ParseAssetName(asset)
+	return state.Binary{
+		Version:     version,
+		Arch:        arch,
+		Asset:       asset,
+		Tags:        inst.Info.Tags,
+		SHA256:      inst.SHA256,
+		Verified:    verified,
+		InstalledAt: time.Now().UTC(),
+	}
+}
+
+func (a *App) printSelfCheck(bin state.Binary) {
+	fmt.Fprintf(a.Out, "新 sing-box 自检通过：版本 %s，构建标签 %s\n", bin.Version, strings.Join(bin.Tags, ","))
+}
+
+func (a *App) archiveCache() singbox.ArchiveCache {
+	return singbox.ArchiveCache{Dir: a.Paths.CacheDir, Keep: archiveCacheKeep}
+}
+
+func (a *App) installSingBoxArchive(ctx context.Context, opts InstallOptions, plat singbox.Platform, destPath string) (state.Binary, error) {
+	asset := filepath.Base(opts.Archive)
+	version := opts.Version
+	v, arch, ok := singbox.ParseAssetName(asset)
+	if ok {
+		if !plat.Supports(arch) {
+			return state.Binary{}, fmt.Errorf("压缩包架构 %s 与本机（%s）不匹配", arch, strings.Join(plat.Archs, "/"))
+		}
+		if version == "" {
+			version = v
+		}
+	} else if version == "" {
+		return state.Binary{}, fmt.Errorf("无法从文件名 %s 识别 sing-box 版本，请通过 --version 指定", asset)
+	}
+
+	var want string
+	switch {
+	case opts.ChecksumFile != "":
+		sum, err := singbox.ReadChecksumFile(opts.ChecksumFile, asset)
+		if err != nil {
+			return state.Binary{}, err
+		}
+		want = sum
+	case opts.InsecureSkipVerify:
+		fmt.Fprintln(a.Err, "警告：离线安装未指定 --checksum-file，已按 --insecure-skip-verify 跳过摘要校验。")
+	default:
+		return state.Binary{}, fmt.Errorf("离线安装无法校验 %s：%w", asset, errNoChecksum)
+	}
+
+	inst, err := singbox.InstallArchive(ctx, opts.Archive, singbox.InstallSpec{
+		Version:  version,
+		Asset:    asset,
+		DestPath: destPath,
+		SHA256:   want,
+	})
+	if err != nil {
+		return state.Binary{}, err
+	}
+
+	return newBinaryState(version, asset, inst, want != ""), nil
+}
+
+// expectedSHA256 返回下载后要比对的摘要：校验文件优先，其次 release API 提供的摘要；
+// 两者都没有时拒绝安装，除非指定了 --insecure-skip-verify。
+func (a *App) expectedSHA256(opts InstallOptions, asset singbox.Asset, relErr error) (string, error) {
+	if opts.ChecksumFile != "" {
+		return singbox.ReadChecksumFile(opts.ChecksumFile, asset.Name)
+	}
+	if relErr == nil && asset.SHA256 != "" {
+		return asset.SHA256, nil
+	}
+
+	reason := fmt.Sprintf("release 未提供 %s 的摘要", asset.Name)
+	if relErr != nil {
+		reason = fmt.Sprintf("无法获取 release 信息（%v）", relErr)
+	}
+	if !opts.InsecureSkipVerify {
+		return "", fmt.Errorf("%s，无法校验安装包：%w", reason, errNoChecksum)
+	}
+	fmt.Fprintf(a.Err, "警告：%s，已按 --insecure-skip-verify 跳过摘要校验。\n", reason)
+	return "", nil
+}
+
+func (a *App) saveBinaryState(b state.Binary, channel string) error {
+	return a.updateState(func(st *state.State) {
+		st.SingBox = b
+		if channel != "" {
+			st.Channel = channel
+		}
+	})
+}
+
+func (a *App) updateState(fn func(*state.State)) error {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	fn(&st)
+	return state.Save(a.Paths.StatePath, st)
+}
+
+// channel 返回生效的更新通道：显式指定优先，其次 state.json 中记录的通道。
+func (a *App) channel(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, singbox.ValidateChannel(explicit)
+	}
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return "", err
+	}
+	if st.Channel != "" {
+		return st.Channel, nil
+	}
+	return singbox.ChannelStable, nil
+}
+
+func (a *App) versionCachePath(channel string) string {
+	if channel == singbox.ChannelStable {
+		return a.Paths.VersionCachePath
+	}
+	return strings.TrimSuffix(a.Paths.VersionCachePath, ".json") + "." + channel + ".json"
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/state"
+)
+
+// ListInstances 列出本机由本工具管理的所有实例。
+func (a *App) ListInstances(ctx context.Context) error {
+	list, err := a.initSys.Instances()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(a.Out, "未发现由本工具管理的实例。")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "实例\t服务名\t状态\t数据目录")
+	for _, inst := range list {
+		status, _, err := a.initSys.Status(ctx, inst.ServiceName)
+		if err != nil {
+			status = "未知"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", instanceLabel(inst.Name), inst.ServiceName, status, orDash(inst.Home))
+	}
+	return w.Flush()
+}
+
+// handOver 把本实例持有的共享资源（apk 包、服务用户）转交给第一个仍在使用它的其他实例：
+// uses 判断实例是否在使用，take 修改接手实例的 state.json。返回接手实例的服务名，没有实例使用时为空。
+func (a *App) handOver(uses func(state.State) bool, take func(*state.State)) (string, error) {
+	list, err := a.initSys.Instances()
+	if err != nil {
+		return "", err
+	}
+	for _, inst := range list {
+		if inst.ServiceName == a.Paths.ServiceName || inst.Home == "" {
+			continue
+		}
+		p := paths.At(inst.Home, inst.Name).StatePath
+		st, err := state.Load(p)
+		if err != nil {
+			return "", err
+		}
+		if !uses(st) {
+			continue
+		}
+		take(&st)
+		return inst.ServiceName, state.Save(p, st)
+	}
+	return "", nil
+}
+
+// handOverUser 把本工具创建的服务用户 name 转交给同样以它运行的其他实例。
+func (a *App) handOverUser(name string) (string, error) {
+	return a.handOver(
+		func(st state.State) bool { return st.Service.User == name },
+		func(st *state.State) { st.CreatedServiceUser = name },
+	)
+}
+
+func instanceLabel(name string) string {
+	if name == "" {
+		return "(默认)"
+	}
+	return name
+}
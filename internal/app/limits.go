@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const (
+	mib = 1 << 20
+	gib = 1 << 30
+
+	// minMemLimit 为手动指定 GOMEMLIMIT 的下限，过小会让 GC 持续运行。
+	minMemLimit = 32 * mib
+)
+
+// defaultLimits 按物理内存选择默认值：GOMEMLIMIT 取内存的 60%；512 MiB 及以下的主机把 GOGC 调到 50，
+// 1 GiB 及以下的主机降低 sing-box 被 OOM killer 选中的概率；文件描述符上限随内存增长（4096..65536）。
+func defaultLimits(memTotal int64) state.Limits {
+	l := state.Limits{
+		NoFile:   int(min(max(memTotal/mib*64, 4096), 65536)),
+		MemLimit: fmt.Sprintf("%dMiB", memTotal*6/10/mib),
+	}
+	if memTotal <= 512*mib {
+		l.GOGC = "50"
+	}
+	if memTotal <= gib {
+		l.OOMScoreAdj = "-500"
+	}
+	return l
+}
+
+// effectiveLimits 用按内存计算的默认值补齐未设置的项，并转换为写入 conf.d 的形式（GOMEMLIMIT 为 off 时不写入）。
+func effectiveLimits(l state.Limits) (state.Limits, error) {
+	mem, err := system.MemTotal()
+	if err != nil {
+		return state.Limits{}, err
+	}
+	def := defaultLimits(mem)
+
+	out := l
+	if out.NoFile == 0 {
+		out.NoFile = def.NoFile
+	}
+	switch out.MemLimit {
+	case "":
+		out.MemLimit = def.MemLimit
+	case "off":
+		out.MemLimit = ""
+	default:
+		n, err := system.ParseBytes(out.MemLimit)
+		if err != nil {
+			return state.Limits{}, err
+		}
+		out.MemLimit = strconv.FormatInt(n, 10)
+		if n%mib == 0 {
+			out.MemLimit = fmt.Sprintf("%dMiB", n/mib)
+		}
+	}
+	if out.GOGC == "" {
+		out.GOGC = def.GOGC
+	}
+	if out.OOMScoreAdj == "" {
+		out.OOMScoreAdj = def.OOMScoreAdj
+	}
+	return out, nil
+}
+
+// normalizeLimits 把命令行中的 auto 还原为空值（按内存自动）。
+func normalizeLimits(l *state.Limits) {
+	for _, v := range []*string{&l.MemLimit, &l.GOGC, &l.OOMScoreAdj} {
+		if strings.EqualFold(*v, "auto") {
+			*v = ""
+		}
+	}
+}
+
+func validateLimits(l state.Limits) error {
+	if l.NoFile < 0 {
+		return fmt.Errorf("非法的 nofile %d", l.NoFile)
+	}
+	if l.MemLimit != "" && l.MemLimit != "off" {
+		n, err := system.ParseBytes(l.MemLimit)
+		if err != nil {
+			return err
+		}
+		if n < minMemLimit {
+			return fmt.Errorf("GOMEMLIMIT %q 过小（至少 %dMiB）；不限制请使用 off", l.MemLimit, minMemLimit/mib)
+		}
+	}
+	if l.GOGC != "" && l.GOGC != "off" {
+		if n, err := strconv.Atoi(l.GOGC); err != nil || n <= 0 {
+			return fmt.Errorf("非法的 GOGC %q：应为正整数或 off", l.GOGC)
+		}
+	}
+	if l.Nice < -20 || l.Nice > 19 {
+		return fmt.Errorf("非法的 nice %d：范围为 -20..19", l.Nice)
+	}
+	if l.IONice != "" {
+		class, level, hasLevel := strings.Cut(l.IONice, ":")
+		c, err := strconv.Atoi(class)
+		ok := err == nil && c >= 0 && c <= 3
+		if ok && hasLevel {
+			n, err := strconv.Atoi(level)
+			ok = err == nil && n >= 0 && n <= 7
+		}
+		if !ok {
+			return fmt.Errorf("非法的 ionice %q：格式为 class[:level]，class 0..3，level 0..7", l.IONice)
+		}
+	}
+	if l.OOMScoreAdj != "" {
+		if n, err := strconv.Atoi(l.OOMScoreAdj); err != nil || n < -1000 || n > 1000 {
+			return fmt.Errorf("非法的 oom_score_adj %q：范围为 -1000..1000", l.OOMScoreAdj)
+		}
+	}
+	return nil
+}
+
+// formatLimits 输出生效的资源限制，供 service 与 status 显示。
+func formatLimits(l state.Limits) string {
+	nice := "-"
+	if l.Nice != 0 {
+		nice = strconv.Itoa(l.Nice)
+	}
+	return fmt.Sprintf("nofile=%d GOMEMLIMIT=%s GOGC=%s nice=%s ionice=%s oom_score_adj=%s",
+		l.NoFile, orDash(l.MemLimit), orDash(l.GOGC), nice, orDash(l.IONice), orDash(l.OOMScoreAdj))
+}
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/logs"
+	"github.com/pkssssss/alpine-vless/internal/schedule"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const (
+	logRotateJobID = "log-rotate"
+	// logRotateSpec 为 builtin 方式的检查频率；按大小轮转需要比按天更频繁地检查。
+	logRotateSpec = "*/10 * * * *"
+
+	logRotateBuiltin   = "builtin"
+	logRotateLogrotate = "logrotate"
+
+	logrotateDir    = "/etc/logrotate.d"
+	logrotateMarker = "# managed-by: alpine-vless"
+
+	reopenTimeout = 5 * time.Second
+)
+
+var logRotateIntervals = map[string]time.Duration{
+	"":        0,
+	"none":    0,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+type LogRotateOptions struct {
+	MaxSize  string
+	Interval string
+	Keep     int
+	// Backend 为空时自动选择：已安装 logrotate 则使用它，否则使用内置定时任务。
+	Backend string
+}
+
+func (a *App) EnableLogRotate(ctx context.Context, opts LogRotateOptions) error {
+	size, err := system.ParseBytes(opts.MaxSize)
+	if err != nil {
+		return err
+	}
+	if _, ok := logRotateIntervals[opts.Interval]; !ok {
+		return fmt.Errorf("非法的轮转周期 %q：可选 daily、weekly、monthly 或 none", opts.Interval)
+	}
+	if opts.Interval == "none" {
+		opts.Interval = ""
+	}
+	if size == 0 && opts.Interval == "" {
+		return errors.New("--max-size 与 --interval 至少需要设置一个")
+	}
+	if opts.Keep < 1 {
+		return errors.New("--keep 至少为 1")
+	}
+
+	backend := opts.Backend
+	switch backend {
+	case "":
+		backend = logRotateBuiltin
+		if system.CommandExists("logrotate") {
+			backend = logRotateLogrotate
+		}
+	case logRotateBuiltin, logRotateLogrotate:
+	default:
+		return fmt.Errorf("未知的轮转方式 %q：可选 builtin 或 logrotate", backend)
+	}
+
+	cfg := state.LogRotate{Enabled: true, Backend: backend, MaxSize: size, Interval: opts.Interval, Keep: opts.Keep}
+	if backend == logRotateLogrotate {
+		if err := a.writeLogrotateConfig(cfg); err != nil {
+			return err
+		}
+		if err := schedule.Remove(a.jobID(logRotateJobID)); err != nil {
+			return err
+		}
+	} else {
+		cmd, err := a.selfCommand("log-rotate", "run")
+		if err != nil {
+			return err
+		}
+		if err := schedule.Install(ctx, a.jobID(logRotateJobID), logRotateSpec, cmd); err != nil {
+			return err
+		}
+		if err := a.removeLogrotateConfig(); err != nil {
+			return err
+		}
+	}
+
+	if err := a.updateState(func(st *state.State) { st.LogRotate = cfg }); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "已开启日志轮转（%s）：大小上限 %s，周期 %s，保留 %d 个 gzip 归档。\n",
+		backend, orDash(formatSize(size)), orDash(opts.Interval), opts.Keep)
+	return nil
+}
+
+func (a *App) DisableLogRotate() error {
+	if err := a.removeLogRotate(); err != nil {
+		return err
+	}
+	if err := a.updateState(func(st *state.State) { st.LogRotate.Enabled = false }); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.Out, "已关闭日志轮转。")
+	return nil
+}
+
+// removeLogRotate 移除定时任务与 logrotate 配置（卸载时同样调用）。
+func (a *App) removeLogRotate() error {
+	if err := schedule.Remove(a.jobID(logRotateJobID)); err != nil {
+		return err
+	}
+	return a.removeLogrotateConfig()
+}
+
+// RunLogRotate 由定时任务调用，轮转达到大小或周期的日志。
+func (a *App) RunLogRotate(ctx context.Context) error {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	cfg := st.LogRotate
+	if !cfg.Enabled || cfg.Backend != logRotateBuiltin {
+		return nil
+	}
+	pol := logs.RotatePolicy{MaxSize: cfg.MaxSize, Interval: logRotateIntervals[cfg.Interval], Keep: cfg.Keep}
+
+	now := time.Now()
+	last := cfg.LastRotated
+	if last == nil {
+		last = map[string]time.Time{}
+	}
+	var errs []error
+	for _, f := range a.rotateFiles() {
+		name := filepath.Base(f.Path)
+		if last[name].IsZero() {
+			// 首次运行从现在开始计算周期。
+			last[name] = now
+		}
+		due, err := pol.Due(f.Path, last[name], now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+		if err := logs.Rotate(f, pol.Keep); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		last[name] = now
+		fmt.Fprintf(a.Out, "已轮转 %s\n", f.Path)
+	}
+
+	if err := a.updateState(func(st *state.State) { st.LogRotate.LastRotated = last }); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// rotateFiles 列出需要轮转的日志：sing-box.log 重命名后通知 sing-box 重新打开；
+// 服务的 stdout/stderr 日志由 supervise-daemon 或 systemd 持有且不会重新打开，只能复制后截断。
+func (a *App) rotateFiles() []logs.RotateFile {
+	return []logs.RotateFile{
+		{Path: a.Paths.LogPath, Reopen: a.reopenSingBoxLog},
+		{Path: a.Paths.OpenRCOutLogPath, CopyTruncate: true},
+		{Path: a.Paths.OpenRCErrLogPath, CopyTruncate: true},
+	}
+}
+
+// reopenSingBoxLog 向 sing-box 发送 SIGHUP：sing-box 收到后重新加载配置并重新打开日志文件。
+// 以服务用户运行时它无法在数据目录中创建文件，因此先按服务用户的属主创建新的日志文件。
+func (a *App) reopenSingBoxLog(rotated string) error {
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	if err := a.applyOwnership(svc); err != nil {
+		return err
+	}
+	pid, err := a.initSys.MainPID(context.Background(), a.Paths.ServiceName)
+	if err != nil {
+		// 未运行时无需通知，下次启动会重新创建日志文件。
+		return nil
+	}
+	if err := syscall.Kill(pid, syscall.SIGHUP); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return nil
+		}
+		return err
+	}
+
+	// 等待 sing-box 关闭旧文件，避免压缩旧文件时它仍在向其写入。
+	deadline := time.Now().Add(reopenTimeout)
+	for time.Now().Before(deadline) {
+		open, err := system.HasOpenFile(pid, rotated)
+		if errors.Is(err, os.ErrNotExist) {
+			// 进程已退出，重启后会打开新的日志文件。
+			return nil
+		}
+		if err != nil || !open {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("sing-box 在 %s 内未重新打开 %s", reopenTimeout, a.Paths.LogPath)
+}
+
+func (a *App) logrotatePath() string {
+	return filepath.Join(logrotateDir, a.Paths.ServiceName)
+}
+
+func (a *App) writeLogrotateConfig(cfg state.LogRotate) error {
+	path := a.logrotatePath()
+	if b, err := os.ReadFile(path); err == nil && !bytes.Contains(b, []byte(logrotateMarker)) {
+		return fmt.Errorf("检测到已有 %s，但不是本工具管理，拒绝覆盖", path)
+	}
+
+	pol := logs.RotatePolicy{MaxSize: cfg.MaxSize, Interval: logRotateIntervals[cfg.Interval], Keep: cfg.Keep}
+	reopen := fmt.Sprintf(`pid=$(%s) && kill -HUP "$pid" 2>/dev/null || true`, a.initSys.MainPIDShell(a.Paths.ServiceName))
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	var create string
+	if svc.User != "" {
+		u, err := system.LookupUser(svc.User)
+		if err != nil {
+			return err
+		}
+		create = fmt.Sprintf("0640 %s %s", u.Name, u.Group)
+	}
+	content := logs.LogrotateConfig(logrotateMarker, a.rotateFiles(), pol, reopen, create)
+
+	if err := system.MkdirAll0755(logrotateDir); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// refreshLogrotateConfig 在服务用户变化后重写 logrotate 配置（create 的属主随之变化）。
+func (a *App) refreshLogrotateConfig() error {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	if !st.LogRotate.Enabled || st.LogRotate.Backend != logRotateLogrotate {
+		return nil
+	}
+	return a.writeLogrotateConfig(st.LogRotate)
+}
+
+func (a *App) removeLogrotateConfig() error {
+	path := a.logrotatePath()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !bytes.Contains(b, []byte(logrotateMarker)) {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+func formatSize(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return system.FormatBytes(n)
+}
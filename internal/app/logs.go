@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkssssss/alpine-vless/internal/logs"
+)
+
+const defaultLogLines = 50
+
+type LogOptions struct {
+	Lines  int
+	Follow bool
+	// Level 为最低显示级别，为空显示全部。
+	Level string
+	// File 选择日志文件：all（合并三个文件）、sing-box、stdout、stderr。
+	File string
+}
+
+func (a *App) Logs(ctx context.Context) error {
+	return a.ShowLogs(ctx, LogOptions{Lines: defaultLogLines})
+}
+
+func (a *App) FollowLogs(ctx context.Context) error {
+	return a.ShowLogs(ctx, LogOptions{Lines: defaultLogLines, Follow: true})
+}
+
+// ShowLogs 输出最近的日志；合并查看时按时间戳排序并标注来源。Follow 模式持续输出直到 ctx 取消。
+func (a *App) ShowLogs(ctx context.Context, opts LogOptions) error {
+	srcs, err := a.logSources(opts.File)
+	if err != nil {
+		return err
+	}
+	minLevel := logs.LevelTrace
+	if opts.Level != "" {
+		if minLevel, err = logs.ParseLevel(opts.Level); err != nil {
+			return err
+		}
+	}
+
+	emit := func(e logs.Entry) {
+		if len(srcs) > 1 {
+			fmt.Fprintf(a.Out, "[%s] %s\n", e.Source, e.Line)
+		} else {
+			fmt.Fprintln(a.Out, e.Line)
+		}
+	}
+
+	entries, offsets, err := logs.Tail(srcs, opts.Lines, minLevel)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		emit(e)
+	}
+	if !opts.Follow {
+		if len(entries) == 0 {
+			fmt.Fprintln(a.Out, "（暂无日志）")
+		}
+		return nil
+	}
+	return logs.Follow(ctx, srcs, offsets, minLevel, emit)
+}
+
+func (a *App) logSources(file string) ([]logs.Source, error) {
+	all := []logs.Source{
+		{Name: "sing-box", Path: a.Paths.LogPath, Default: logs.LevelInfo},
+		{Name: "stdout", Path: a.Paths.OpenRCOutLogPath, Default: logs.LevelInfo},
+		{Name: "stderr", Path: a.Paths.OpenRCErrLogPath, Default: logs.LevelError},
+	}
+	if file == "" || file == "all" {
+		return all, nil
+	}
+	for _, s := range all {
+		if s.Name == file {
+			return []logs.Source{s}, nil
+		}
+	}
+	return nil, fmt.Errorf("未知的日志文件 %q：可选 all、sing-box、stdout、stderr", file)
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+)
+
+type RotateOptions struct {
+	// Port 非 0 时同时更换监听端口（需要重启服务）。
+	Port int
+}
+
+func (a *App) Rotate(ctx context.Context) error {
+	return a.RotateWith(ctx, RotateOptions{})
+}
+
+// RotateWith 重新生成 UUID、Reality 密钥对与 short_id；端口不变时热重载，不重启进程与服务。
+func (a *App) RotateWith(ctx context.Context, opts RotateOptions) error {
+	if !a.IsInstalled() {
+		return errors.New("尚未部署，请先添加配置")
+	}
+	if opts.Port < 0 || opts.Port > 65535 {
+		return fmt.Errorf("非法的端口 %d", opts.Port)
+	}
+
+	cfg, err := singbox.ReadConfig(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+	oldPorts, err := singbox.ListenPorts(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+	node, err := cfg.Node.RotateCredentials()
+	if err != nil {
+		return err
+	}
+	if opts.Port != 0 {
+		node.Port = opts.Port
+	}
+
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	newConfig := a.Paths.ConfigPath + ".new"
+	if err := singbox.WriteConfig(newConfig, a.Paths.LogPath, node, st.SingBox.Version); err != nil {
+		return err
+	}
+	if err := singbox.CheckConfig(ctx, a.Paths.SingBoxPath, newConfig); err != nil {
+		_ = os.Remove(newConfig)
+		return fmt.Errorf("新配置校验失败，未作改动: %w", err)
+	}
+	if err := a.commitConfig(cfg.Raw); err != nil {
+		return err
+	}
+
+	if err := a.applyConfig(ctx, oldPorts); err != nil {
+		if rbErr := a.restoreConfig(ctx, oldPorts); rbErr != nil {
+			return fmt.Errorf("应用新配置失败: %v；恢复旧配置也失败: %w", err, rbErr)
+		}
+		return fmt.Errorf("应用新配置失败，已恢复旧配置: %w", err)
+	}
+
+	pub, err := singbox.RealityPublicKeyFromPrivateKey(node.RealityPrivateKey)
+	if err != nil {
+		return err
+	}
+	ip, _ := singbox.PublicIP(ctx, a.httpClient)
+	fmt.Fprintf(a.Out, "已更换凭据，旧配置保留为 %s；旧的导入链接已失效：\n", a.Paths.ConfigPrevPath)
+	fmt.Fprintln(a.Out, node.URL(ip, pub))
+	return nil
+}
+
+// applyConfig 让已通过 CheckConfig 的 config.json 生效：监听端口不变且服务在运行时发送 SIGHUP 热重载；
+// 端口变化时重写服务文件（所需 capability 取决于端口）并重启。oldPorts 为修改前的监听端口。
+func (a *App) applyConfig(ctx context.Context, oldPorts []int) error {
+	ports, err := singbox.ListenPorts(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if len(ports) == 0 {
+		return errors.New("配置中没有监听端口")
+	}
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+
+	if !slices.Equal(ports, oldPorts) {
+		fmt.Fprintf(a.Out, "监听端口由 %v 变为 %v，需要重启服务。\n", oldPorts, ports)
+		if err := a.installService(ctx, svc); err != nil {
+			return err
+		}
+		return a.restartHealthy(ctx, ports[0])
+	}
+
+	if _, running, err := a.initSys.Status(ctx, a.Paths.ServiceName); err != nil || !running {
+		return a.restartHealthy(ctx, ports[0])
+	}
+	if err := a.applyOwnership(svc); err != nil {
+		return err
+	}
+	if err := a.initSys.Reload(ctx, a.Paths.ServiceName); err != nil {
+		// 旧版 init 脚本没有 reload：重写服务文件后重启，下次即可热重载。
+		fmt.Fprintf(a.Out, "热重载失败（%v），改为重启服务。\n", err)
+		if err := a.installService(ctx, svc); err != nil {
+			return err
+		}
+		return a.restartHealthy(ctx, ports[0])
+	}
+	fmt.Fprintln(a.Out, "已热重载配置（SIGHUP），sing-box 进程与服务均未重启。")
+	return a.waitHealthy(ctx, ports[0])
+}
+
+// restoreConfig 用 config.json.prev 恢复旧配置并重启服务。
+func (a *App) restoreConfig(ctx context.Context, oldPorts []int) error {
+	if err := os.Rename(a.Paths.ConfigPrevPath, a.Paths.ConfigPath); err != nil {
+		return err
+	}
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	if err := a.installService(ctx, svc); err != nil {
+		return err
+	}
+	if len(oldPorts) == 0 {
+		return a.restartService(ctx)
+	}
+	return a.restartHealthy(ctx, oldPorts[0])
+}
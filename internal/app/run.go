@@ -4,17 +4,23 @@ import (
 	"bufio"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"runtime"
-	"time"
+	"strings"
 
+	"github.com/pkssssss/alpine-vless/internal/apk"
+	"github.com/pkssssss/alpine-vless/internal/bbr"
+	"github.com/pkssssss/alpine-vless/internal/buildinfo"
+	"github.com/pkssssss/alpine-vless/internal/initsys"
 	"github.com/pkssssss/alpine-vless/internal/menu"
-	"github.com/pkssssss/alpine-vless/internal/openrc"
 	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/schedule"
 	"github.com/pkssssss/alpine-vless/internal/singbox"
-	"github.com/pkssssss/alpine-vless/internal/bbr"
+	"github.com/pkssssss/alpine-vless/internal/state"
 	"github.com/pkssssss/alpine-vless/internal/system"
 )
 
@@ -24,34 +30,56 @@ type App struct {
 	Err   io.Writer
 
 	httpClient *http.Client
+	source     singbox.Source
+	initSys    initsys.System
 }
 
-func Run(ctx context.Context, in io.Reader, out, errOut io.Writer) error {
-	if runtime.GOOS != "linux" {
-		return errors.New("仅支持在 Linux（Alpine）运行")
+func Run(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
+	a := &App{
+		Out:        out,
+		Err:        errOut,
+		httpClient: newHTTPClient(),
+		source:     singbox.SourceFromEnv(),
+	}
+
+	instance, args, err := instanceArg(args)
+	if err != nil {
+		return err
 	}
-	if !system.IsRoot() {
-		return errors.New("需要 root 权限运行")
+
+	// 以下命令与本机部署无关，不要求 root 与 init 系统。
+	if len(args) > 0 {
+		switch args[0] {
+		case "bundle", "version", "self-update":
+			return a.runCommand(ctx, args[0], args[1:])
+		}
 	}
-	if !system.IsAlpine() {
-		return errors.New("仅支持 Alpine Linux")
+
+	if runtime.GOOS != "linux" {
+		return errors.New("仅支持在 Linux 上运行")
 	}
-	if !system.CommandExists("rc-service") || !system.CommandExists("rc-update") {
-		return errors.New("未检测到 OpenRC（缺少 rc-service/rc-update）")
+	// run 为前台/容器模式，自行监督 sing-box，不需要 init 系统与 root。
+	if len(args) == 0 || args[0] != "run" {
+		if !system.IsRoot() {
+			return errors.New("需要 root 权限运行")
+		}
+		if a.initSys, err = detectInitSystem(); err != nil {
+			return err
+		}
 	}
 
-	p, err := paths.Discover()
+	p, err := paths.Discover(instance)
+	if err != nil {
+		return err
+	}
+	st, err := state.Load(p.StatePath)
 	if err != nil {
 		return err
 	}
+	a.Paths = p.ForSource(st.Source)
 
-	a := &App{
-		Paths: p,
-		Out:   out,
-		Err:   errOut,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	if len(args) > 0 {
+		return a.runCommand(ctx, args[0], args[1:])
 	}
 
 	if !system.FileExists(a.Paths.ConfigPath) {
@@ -60,12 +88,205 @@ func Run(ctx context.Context, in io.Reader, out, errOut io.Writer) error {
 			return err
 		}
 	} else if !a.IsInstalled() {
-		fmt.Fprintln(a.Out, "检测到已有配置，但 OpenRC 服务未安装或非本工具管理；可从菜单选择“添加配置(覆盖)”或“卸载”。")
+		fmt.Fprintln(a.Out, "检测到已有配置，但服务未安装或非本工具管理；可从菜单选择“添加配置(覆盖)”或“卸载”。")
 	}
 
 	return menu.Run(ctx, bufio.NewReader(in), out, errOut, a)
 }
 
+// instanceArg 取出位于命令之前的 --instance NAME（或 --instance=NAME），未指定时读取 ALPINE_VLESS_INSTANCE。
+func instanceArg(args []string) (string, []string, error) {
+	if len(args) > 0 {
+		if v, ok := strings.CutPrefix(args[0], "--instance="); ok {
+			return v, args[1:], nil
+		}
+		if args[0] == "--instance" {
+			if len(args) < 2 {
+				return "", nil, errors.New("--instance 需要实例名")
+			}
+			return args[1], args[2:], nil
+		}
+	}
+	return os.Getenv("ALPINE_VLESS_INSTANCE"), args, nil
+}
+
+func (a *App) runCommand(ctx context.Context, name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Err)
+
+	switch name {
+	case "add", "install":
+		opts := a.installFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.AddWith(ctx, *opts)
+	case "upgrade":
+		opts := a.installFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.UpgradeWith(ctx, *opts)
+	case "version":
+		fmt.Fprintln(a.Out, buildinfo.String())
+		return nil
+	case "status":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.Status(ctx)
+	case "run":
+		install := a.installFlags(fs)
+		port := fs.Int("port", 0, "监听端口（默认首次生成时随机选择；已有配置时更新端口）")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.Foreground(ctx, ForegroundOptions{Install: *install, Port: *port})
+	case "rotate":
+		var opts RotateOptions
+		fs.IntVar(&opts.Port, "port", 0, "同时更换监听端口（需要重启服务，默认保持不变）")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.RotateWith(ctx, opts)
+	case "instances":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.ListInstances(ctx)
+	case "auto-update":
+		win := fs.String("window", "", "维护窗口（本地时间）HH:MM-HH:MM，默认 "+defaultUpdateWindow)
+		channel := fs.String("channel", "", "更新通道：stable、beta 或固定次版本如 1.10")
+		if len(args) == 0 {
+			return errors.New("用法: auto-update enable|disable|run")
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		switch args[0] {
+		case "enable":
+			return a.EnableAutoUpdate(ctx, *win, *channel)
+		case "disable":
+			return a.DisableAutoUpdate()
+		case "run":
+			return a.RunAutoUpdate(ctx)
+		default:
+			return fmt.Errorf("未知子命令: auto-update %s", args[0])
+		}
+	case "log-rotate":
+		var opts LogRotateOptions
+		fs.StringVar(&opts.MaxSize, "max-size", "10M", "单个日志文件的大小上限（0 为不按大小轮转）")
+		fs.StringVar(&opts.Interval, "interval", "daily", "按时间轮转的周期：daily、weekly、monthly 或 none")
+		fs.IntVar(&opts.Keep, "keep", 5, "每个日志保留的 gzip 归档数")
+		fs.StringVar(&opts.Backend, "backend", "", "轮转方式：builtin（定时任务）或 logrotate（默认已安装 logrotate 时使用）")
+		if len(args) == 0 {
+			return errors.New("用法: log-rotate enable|disable|run")
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		switch args[0] {
+		case "enable":
+			return a.EnableLogRotate(ctx, opts)
+		case "disable":
+			return a.DisableLogRotate()
+		case "run":
+			return a.RunLogRotate(ctx)
+		default:
+			return fmt.Errorf("未知子命令: log-rotate %s", args[0])
+		}
+	case "logs":
+		var opts LogOptions
+		fs.IntVar(&opts.Lines, "n", defaultLogLines, "显示最后多少行（0 为全部）")
+		fs.BoolVar(&opts.Follow, "f", false, "持续跟踪新日志（Ctrl+C 退出）")
+		fs.StringVar(&opts.Level, "level", "", "最低显示级别：trace/debug/info/warn/error/fatal/panic")
+		fs.StringVar(&opts.File, "file", "all", "日志文件：all（按时间合并）、sing-box、stdout、stderr")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.ShowLogs(ctx, opts)
+	case "service":
+		svc, err := a.serviceOptions()
+		if err != nil {
+			return err
+		}
+		fs.IntVar(&svc.RespawnDelay, "respawn-delay", svc.RespawnDelay, "崩溃后等待多少秒再重启")
+		fs.IntVar(&svc.RespawnMax, "respawn-max", svc.RespawnMax, "respawn-period 秒内最多重启次数（0 为不限）")
+		fs.IntVar(&svc.RespawnPeriod, "respawn-period", svc.RespawnPeriod, "统计重启次数的时间窗口（秒）")
+		fs.StringVar(&svc.User, "user", svc.User, "以该系统用户运行 sing-box（不存在时创建同名用户与组；root 表示恢复以 root 运行）")
+		fs.IntVar(&svc.Limits.NoFile, "nofile", svc.Limits.NoFile, "最大打开文件数（rc_ulimit -n，0 为按内存自动）")
+		fs.StringVar(&svc.Limits.MemLimit, "gomemlimit", svc.Limits.MemLimit, "Go 软内存上限 GOMEMLIMIT，如 150M；auto 按内存自动（默认），off 不限制")
+		fs.StringVar(&svc.Limits.GOGC, "gogc", svc.Limits.GOGC, "GOGC 百分比或 off；auto 按内存自动（默认）")
+		fs.IntVar(&svc.Limits.Nice, "nice", svc.Limits.Nice, "调度优先级 -20..19（0 为不调整）")
+		fs.StringVar(&svc.Limits.IONice, "ionice", svc.Limits.IONice, "I/O 优先级 class[:level]，如 2:7（为空不调整）")
+		fs.StringVar(&svc.Limits.OOMScoreAdj, "oom-score-adj", svc.Limits.OOMScoreAdj, "oom_score_adj -1000..1000；auto 按内存自动（默认）")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return a.ConfigureService(ctx, svc)
+	case "self-update":
+		var opts SelfUpdateOptions
+		repo := fs.String("repo", "", "release 来源仓库 owner/name（默认 "+defaultSelfRepo+"）")
+		apiURL := fs.String("api-url", "", "release API 地址（覆盖 --repo）")
+		downloadURL := fs.String("download-url", "", "release 下载地址前缀（覆盖 --repo）")
+		fs.StringVar(&opts.Version, "version", "", "更新到指定版本（默认最新版）")
+		fs.StringVar(&opts.FromFile, "from-file", "", "从本地文件更新（不访问网络）")
+		fs.StringVar(&opts.ChecksumFile, "checksum-file", "", "sha256sum 格式的校验文件")
+		fs.BoolVar(&opts.InsecureSkipVerify, "insecure-skip-verify", false, "--from-file 时不提供校验文件也替换（不校验，不安全）")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		opts.Source = selfSource(*repo)
+		if *apiURL != "" {
+			opts.Source.APIBaseURL = *apiURL
+		}
+		if *downloadURL != "" {
+			opts.Source.DownloadBaseURL = *downloadURL
+		}
+		return a.SelfUpdate(ctx, opts)
+	case "bundle":
+		var opts BundleOptions
+		versions := fs.String("version", "", "sing-box 版本，逗号分隔（默认最新版）")
+		archs := fs.String("arch", "amd64,arm64", "架构，逗号分隔（如 amd64,arm64,armv7）")
+		fs.StringVar(&opts.Libc, "libc", "musl", "目标系统的 libc（musl/glibc）")
+		fs.StringVar(&opts.Output, "o", "alpine-vless-bundle.tar", "输出文件")
+		fs.StringVar(&a.source.APIBaseURL, "api-url", a.source.APIBaseURL, "release API 地址（镜像）")
+		fs.StringVar(&a.source.DownloadBaseURL, "download-url", a.source.DownloadBaseURL, "release 下载地址前缀（镜像或 GitHub 代理）")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		opts.Versions = splitList(*versions)
+		opts.Archs = splitList(*archs)
+		return a.Bundle(ctx, opts)
+	default:
+		return fmt.Errorf("未知命令: %s", name)
+	}
+}
+
+func (a *App) installFlags(fs *flag.FlagSet) *InstallOptions {
+	opts := &InstallOptions{}
+	fs.StringVar(&opts.Version, "version", "", "sing-box 版本（默认最新版）")
+	fs.StringVar(&opts.ChecksumFile, "checksum-file", "", "sha256sum 格式的校验文件（默认使用 GitHub release 提供的摘要）")
+	fs.StringVar(&opts.Archive, "from-archive", "", "从本地 sing-box 压缩包安装（不访问网络）")
+	fs.StringVar(&opts.Bundle, "bundle", "", "从 bundle 命令生成的离线包安装（不访问网络）")
+	fs.StringVar(&opts.Channel, "channel", "", "更新通道：stable、beta 或固定次版本如 1.10（记录到 state.json）")
+	fs.BoolVar(&opts.InsecureSkipVerify, "insecure-skip-verify", false, "没有 release 摘要与校验文件时仍然安装（不校验，不安全）")
+	fs.StringVar(&opts.Source, "source", "", "安装来源：github（官方 release，默认）或 apk（Alpine community 仓库）")
+	fs.StringVar(&a.source.APIBaseURL, "api-url", a.source.APIBaseURL, "release API 地址（镜像）")
+	fs.StringVar(&a.source.DownloadBaseURL, "download-url", a.source.DownloadBaseURL, "release 下载地址前缀（镜像或 GitHub 代理）")
+	return opts
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func (a *App) IsInstalled() bool {
 	if !system.FileExists(a.Paths.ConfigPath) {
 		return false
@@ -73,38 +294,29 @@ func (a *App) IsInstalled() bool {
 	if !system.FileExists(a.Paths.SingBoxPath) {
 		return false
 	}
-	return openrc.IsManagedServiceFile(a.Paths.ServiceFile)
+	return a.initSys.IsInstalled(a.Paths)
 }
 
 func (a *App) Add(ctx context.Context) error {
-	arch, err := singbox.DetectArch(runtime.GOARCH)
-	if err != nil {
-		return err
-	}
+	return a.AddWith(ctx, InstallOptions{})
+}
 
+func (a *App) AddWith(ctx context.Context, opts InstallOptions) error {
 	if err := system.MkdirAll0700(a.Paths.RootDir); err != nil {
 		return err
 	}
 
-	version, err := singbox.LatestVersion(ctx, a.httpClient)
+	prev, bin, source, err := a.ensureSingBox(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	if err := singbox.Install(ctx, a.httpClient, singbox.InstallSpec{
-		Version:  version,
-		Arch:     arch,
-		DestPath: a.Paths.SingBoxPath,
-	}); err != nil {
-		return err
-	}
-
 	node, err := singbox.NewDefaultNode(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err := singbox.WriteConfig(a.Paths.ConfigPath, a.Paths.LogPath, node); err != nil {
+	if err := singbox.WriteConfig(a.Paths.ConfigPath, a.Paths.LogPath, node, bin.Version); err != nil {
 		return err
 	}
 
@@ -112,17 +324,27 @@ func (a *App) Add(ctx context.Context) error {
 		return err
 	}
 
-	if err := openrc.CleanupLegacyManaged(ctx); err != nil {
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	if err := a.installService(ctx, svc); err != nil {
 		return err
 	}
-	if err := openrc.InstallServiceFile(a.Paths); err != nil {
+	if err := a.initSys.EnableAndStart(ctx, a.Paths.ServiceName); err != nil {
 		return err
 	}
-	if err := openrc.EnableAndStart(ctx, a.Paths.ServiceName); err != nil {
+	if err := a.verifyPrivileges(ctx, svc); err != nil {
+		return err
+	}
+	if err := a.cleanupPreviousSource(ctx, prev, source); err != nil {
 		return err
 	}
 
-	ip, _ := singbox.PublicIP(ctx, a.httpClient)
+	var ip string
+	if !opts.offline() {
+		ip, _ = singbox.PublicIP(ctx, a.httpClient)
+	}
 	pub, err := singbox.RealityPublicKeyFromPrivateKey(node.RealityPrivateKey)
 	if err != nil {
 		return err
@@ -133,6 +355,56 @@ func (a *App) Add(ctx context.Context) error {
 	return nil
 }
 
+// ensureSingBox 按安装来源安装（或沿用已安装的）sing-box 并记录到 state.json；prev 为安装前的状态。
+func (a *App) ensureSingBox(ctx context.Context, opts InstallOptions) (prev state.State, bin state.Binary, source string, err error) {
+	if err := singbox.ValidateChannel(opts.Channel); err != nil {
+		return state.State{}, state.Binary{}, "", err
+	}
+	source, err = a.installSource(opts.Source)
+	if err != nil {
+		return state.State{}, state.Binary{}, "", err
+	}
+	prev, err = state.Load(a.Paths.StatePath)
+	if err != nil {
+		return state.State{}, state.Binary{}, "", err
+	}
+	a.Paths = a.Paths.ForSource(source)
+
+	added := false
+	if source == paths.SourceAPK {
+		bin, added, err = a.installSingBoxAPK(ctx, opts)
+	} else {
+		opts.keepInstalled = true
+		bin, err = a.installSingBox(ctx, opts, a.Paths.SingBoxPath)
+		if err == nil {
+			a.printSelfCheck(bin)
+		}
+	}
+	switch {
+	case errors.Is(err, errAlreadyInstalled):
+		fmt.Fprintf(a.Out, "已安装 sing-box %s，跳过下载。\n", bin.Version)
+		if opts.Channel != "" {
+			if err := a.updateState(func(st *state.State) { st.Channel = opts.Channel }); err != nil {
+				return state.State{}, state.Binary{}, "", err
+			}
+		}
+	case err != nil:
+		return state.State{}, state.Binary{}, "", err
+	default:
+		if err := a.saveBinaryState(bin, opts.Channel); err != nil {
+			return state.State{}, state.Binary{}, "", err
+		}
+	}
+	if err := a.updateState(func(st *state.State) {
+		owned := prev.Source == paths.SourceAPK && prev.PackageOwned
+		st.Source = source
+		st.PackageOwned = source == paths.SourceAPK && (added || owned)
+	}); err != nil {
+		return state.State{}, state.Binary{}, "", err
+	}
+	return prev, bin, source, nil
+}
+
 func (a *App) Show(ctx context.Context) error {
 	cfg, err := singbox.ReadConfig(a.Paths.ConfigPath)
 	if err != nil {
@@ -150,9 +422,56 @@ func (a *App) Show(ctx context.Context) error {
 }
 
 func (a *App) Uninstall(ctx context.Context) error {
-	if err := openrc.StopDisableAndRemove(ctx, a.Paths); err != nil {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
 		return err
 	}
+
+	if err := a.initSys.Remove(ctx, a.Paths); err != nil {
+		return err
+	}
+	if err := schedule.Remove(a.jobID(autoUpdateJobID)); err != nil {
+		return err
+	}
+	if err := a.removeLogRotate(); err != nil {
+		return err
+	}
+	// apk 包与服务用户可能被其他实例共用：仍有实例使用时把所有权交给它，否则删除。
+	if st.Source == paths.SourceAPK {
+		if !st.PackageOwned {
+			fmt.Fprintln(a.Out, "sing-box 包并非由本工具安装，已保留。")
+		} else {
+			heir, err := a.handOver(
+				func(o state.State) bool { return o.Source == paths.SourceAPK },
+				func(o *state.State) { o.PackageOwned = true },
+			)
+			if err != nil {
+				return err
+			}
+			if heir != "" {
+				fmt.Fprintf(a.Out, "%s 仍在使用 sing-box 包，已保留并交由其管理。\n", heir)
+			} else {
+				if err := apk.Del(ctx, apk.SingBox); err != nil {
+					return err
+				}
+				fmt.Fprintln(a.Out, "已通过 apk 删除 sing-box 包。")
+			}
+		}
+	}
+	if u := st.CreatedServiceUser; u != "" {
+		heir, err := a.handOverUser(u)
+		if err != nil {
+			return err
+		}
+		if heir != "" {
+			fmt.Fprintf(a.Out, "%s 仍以服务用户 %s 运行，已保留并交由其管理。\n", heir, u)
+		} else {
+			if err := system.DeleteSystemUser(ctx, u); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.Out, "已删除服务用户 %s。\n", u)
+		}
+	}
 	if err := system.RemoveAll(a.Paths.RootDir); err != nil {
 		return err
 	}
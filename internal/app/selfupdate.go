@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/buildinfo"
+	"github.com/pkssssss/alpine-vless/internal/selfupdate"
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const defaultSelfRepo = "pkssssss/alpine-vless"
+
+var checksumAssetNames = []string{"SHA256SUMS", "sha256sums.txt", "checksums.txt"}
+
+type SelfUpdateOptions struct {
+	Version      string
+	FromFile     string
+	ChecksumFile string
+	// InsecureSkipVerify 允许 --from-file 不提供校验文件。
+	InsecureSkipVerify bool
+	Source             singbox.Source
+}
+
+// selfSource 返回本项目 release 的地址；repo 为空时读取 ALPINE_VLESS_SELF_REPO。
+func selfSource(repo string) singbox.Source {
+	if repo == "" {
+		repo = strings.TrimSpace(os.Getenv("ALPINE_VLESS_SELF_REPO"))
+	}
+	if repo == "" {
+		repo = defaultSelfRepo
+	}
+	s := singbox.Source{
+		APIBaseURL:      "https://api.github.com/repos/" + repo,
+		DownloadBaseURL: "https://github.com/" + repo + "/releases/download",
+	}
+	if v := strings.TrimSpace(os.Getenv("ALPINE_VLESS_SELF_API_URL")); v != "" {
+		s.APIBaseURL = v
+	}
+	if v := strings.TrimSpace(os.Getenv("ALPINE_VLESS_SELF_DOWNLOAD_URL")); v != "" {
+		s.DownloadBaseURL = v
+	}
+	return s
+}
+
+func selfAssetName() string {
+	return "alpine-vless-linux-" + runtime.GOARCH
+}
+
+func (a *App) SelfUpdate(ctx context.Context, opts SelfUpdateOptions) error {
+	exe, err := selfupdate.Executable()
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(exe), ".alpine-vless-update-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	var newPath string
+	if opts.FromFile != "" {
+		newPath, err = a.selfUpdateFromFile(opts)
+	} else {
+		newPath, err = a.selfUpdateDownload(ctx, opts, tmpDir)
+	}
+	if err != nil || newPath == "" {
+		return err
+	}
+
+	if err := selfupdate.Replace(ctx, newPath, exe); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "已更新 %s，重新启动以确认版本：\n", exe)
+	return selfupdate.Exec(exe, "version")
+}
+
+func (a *App) selfUpdateFromFile(opts SelfUpdateOptions) (string, error) {
+	if opts.ChecksumFile == "" {
+		if !opts.InsecureSkipVerify {
+			return "", fmt.Errorf("无法校验 %s：%w", opts.FromFile, errNoChecksum)
+		}
+		fmt.Fprintln(a.Err, "警告：未指定 --checksum-file，已按 --insecure-skip-verify 跳过摘要校验。")
+		return opts.FromFile, nil
+	}
+	want, err := singbox.ReadChecksumFile(opts.ChecksumFile, filepath.Base(opts.FromFile))
+	if err != nil {
+		return "", err
+	}
+	if err := verifyFileSHA256(opts.FromFile, want); err != nil {
+		return "", err
+	}
+	return opts.FromFile, nil
+}
+
+func (a *App) selfUpdateDownload(ctx context.Context, opts SelfUpdateOptions, tmpDir string) (string, error) {
+	var rel singbox.Release
+	var err error
+	if opts.Version == "" {
+		rel, err = singbox.LatestRelease(ctx, a.httpClient, opts.Source)
+	} else {
+		rel, err = singbox.FetchRelease(ctx, a.httpClient, opts.Source, opts.Version)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	current := strings.TrimPrefix(buildinfo.Version, "v")
+	if rel.Version == current {
+		fmt.Fprintf(a.Out, "alpine-vless 已是 %s，无需更新。\n", buildinfo.Version)
+		return "", nil
+	}
+	if opts.Version == "" && current != "dev" && singbox.CompareVersions(rel.Version, current) < 0 {
+		fmt.Fprintf(a.Out, "当前版本 %s 比最新发布 %s 更新，跳过（如需降级请使用 --version）。\n", buildinfo.Version, rel.Version)
+		return "", nil
+	}
+
+	name := selfAssetName()
+	asset, ok := rel.Asset(name)
+	if !ok {
+		return "", fmt.Errorf("release %s 中没有 %s", rel.Version, name)
+	}
+
+	want, err := a.selfUpdateDigest(ctx, opts, rel, asset, tmpDir)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(a.Out, "下载 alpine-vless %s（%s）\n", rel.Version, name)
+	path, _, err := singbox.Download(ctx, a.httpClient, singbox.InstallSpec{
+		Source:      opts.Source,
+		Version:     rel.Version,
+		Asset:       name,
+		SHA256:      want,
+		Size:        asset.Size,
+		DownloadDir: tmpDir,
+		Progress:    a.Out,
+	})
+	return path, err
+}
+
+// selfUpdateDigest 依次使用 --checksum-file、API 提供的 asset 摘要、release 中的校验文件；都没有时拒绝更新。
+func (a *App) selfUpdateDigest(ctx context.Context, opts SelfUpdateOptions, rel singbox.Release, asset singbox.Asset, tmpDir string) (string, error) {
+	if opts.ChecksumFile != "" {
+		return singbox.ReadChecksumFile(opts.ChecksumFile, asset.Name)
+	}
+	if asset.SHA256 != "" {
+		return asset.SHA256, nil
+	}
+	for _, n := range checksumAssetNames {
+		sums, ok := rel.Asset(n)
+		if !ok {
+			continue
+		}
+		path, _, err := singbox.Download(ctx, a.httpClient, singbox.InstallSpec{
+			Source:      opts.Source,
+			Version:     rel.Version,
+			Asset:       sums.Name,
+			DownloadDir: tmpDir,
+		})
+		if err != nil {
+			return "", err
+		}
+		return singbox.ReadChecksumFile(path, asset.Name)
+	}
+	return "", fmt.Errorf("release %s 未提供 %s 的摘要，拒绝更新（可通过 --checksum-file 指定）", rel.Version, asset.Name)
+}
+
+func verifyFileSHA256(path, want string) error {
+	sum, err := system.SHA256File(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, want) {
+		return fmt.Errorf("%s 校验失败：期望 SHA-256 %s，实际 %s", path, want, sum)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const privilegeCheckTimeout = 10 * time.Second
+
+// defaultService 为未配置过时的监督参数：崩溃 2 秒后重启，60 秒内最多重启 10 次。
+var defaultService = state.Service{
+	RespawnDelay:  2,
+	RespawnMax:    10,
+	RespawnPeriod: 60,
+}
+
+// serviceOptions 返回 state.json 中记录的服务参数，未配置时使用默认值。
+func (a *App) serviceOptions() (state.Service, error) {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return state.Service{}, err
+	}
+	if st.Service.Configured {
+		return st.Service, nil
+	}
+	// 旧版本的 state.json 没有 configured 字段，非零参数仍视为已配置。
+	if st.Service == (state.Service{}) {
+		return defaultService, nil
+	}
+	return st.Service, nil
+}
+
+func validateService(svc state.Service) error {
+	if svc.RespawnDelay < 0 || svc.RespawnMax < 0 || svc.RespawnPeriod < 0 {
+		return errors.New("respawn 参数不能为负数")
+	}
+	return validateLimits(svc.Limits)
+}
+
+// ConfigureService 保存服务参数；已部署时重写服务文件并重启使其生效。
+func (a *App) ConfigureService(ctx context.Context, svc state.Service) error {
+	if svc.User == "root" {
+		svc.User = ""
+	}
+	normalizeLimits(&svc.Limits)
+	if err := validateService(svc); err != nil {
+		return err
+	}
+	svc.Configured = true
+	prev, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+	if err := a.updateState(func(st *state.State) { st.Service = svc }); err != nil {
+		return err
+	}
+	if err := a.refreshLogrotateConfig(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Out, "服务参数: respawn_delay=%d respawn_max=%d respawn_period=%d user=%s\n",
+		svc.RespawnDelay, svc.RespawnMax, svc.RespawnPeriod, orDash(svc.User))
+	if l, err := effectiveLimits(svc.Limits); err == nil {
+		fmt.Fprintf(a.Out, "资源限制: %s\n", formatLimits(l))
+	}
+	if !a.IsInstalled() {
+		fmt.Fprintln(a.Out, "尚未部署，参数将在添加配置时生效。")
+		return a.removeReplacedUser(ctx, prev.CreatedServiceUser, svc.User)
+	}
+
+	if err := a.installService(ctx, svc); err != nil {
+		return err
+	}
+	if err := a.restartService(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "已更新 %s 并重启服务。\n", a.initSys.ServiceFile(a.Paths))
+	if err := a.verifyPrivileges(ctx, svc); err != nil {
+		return err
+	}
+	return a.removeReplacedUser(ctx, prev.CreatedServiceUser, svc.User)
+}
+
+// removeReplacedUser 在改用其他用户运行后，删除之前由本工具创建的服务用户 old。
+func (a *App) removeReplacedUser(ctx context.Context, old, current string) error {
+	if old == "" || old == current {
+		return nil
+	}
+	heir, err := a.handOverUser(old)
+	if err != nil {
+		return err
+	}
+	if heir != "" {
+		fmt.Fprintf(a.Out, "%s 仍以服务用户 %s 运行，已保留并交由其管理。\n", heir, old)
+	} else {
+		if err := system.DeleteSystemUser(ctx, old); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Out, "已删除不再使用的服务用户 %s。\n", old)
+	}
+	return a.updateState(func(st *state.State) {
+		if st.CreatedServiceUser == old {
+			st.CreatedServiceUser = ""
+		}
+	})
+}
+
+// installService 准备服务用户与目录权限，按配置推断需要保留的 capability、按内存补齐资源限制后写入服务文件。
+func (a *App) installService(ctx context.Context, svc state.Service) error {
+	var caps []string
+	if svc.User != "" {
+		u, created, err := system.EnsureSystemUser(ctx, svc.User)
+		if err != nil {
+			return fmt.Errorf("创建服务用户 %s 失败: %w", svc.User, err)
+		}
+		if created {
+			fmt.Fprintf(a.Out, "已创建系统用户与用户组 %s（uid %d）。\n", u.Name, u.UID)
+			if err := a.updateState(func(st *state.State) { st.CreatedServiceUser = u.Name }); err != nil {
+				return err
+			}
+		}
+		svc.Group = u.Group
+		if err := checkTraversable(a.Paths.RootDir); err != nil {
+			return err
+		}
+		caps, err = singbox.RequiredCapabilities(a.Paths.ConfigPath)
+		if err != nil {
+			return err
+		}
+	}
+	if err := a.applyOwnership(svc); err != nil {
+		return err
+	}
+	limits, err := effectiveLimits(svc.Limits)
+	if err != nil {
+		return err
+	}
+	svc.Limits = limits
+	return a.initSys.Install(ctx, a.Paths, svc, caps)
+}
+
+// restartService 在重启前修正文件属主（升级/回滚会以 root 重写配置）。
+func (a *App) restartService(ctx context.Context) error {
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	if err := a.applyOwnership(svc); err != nil {
+		return err
+	}
+	return a.initSys.Restart(ctx, a.Paths.ServiceName)
+}
+
+// applyOwnership 调整数据目录权限：以服务用户运行时，数据目录与配置归 root:<组> 且组只读，
+// sing-box 需要写入的日志归服务用户；二进制仍归 root，服务用户无法替换。
+func (a *App) applyOwnership(svc state.Service) error {
+	logs := []string{a.Paths.LogPath, a.Paths.OpenRCOutLogPath, a.Paths.OpenRCErrLogPath}
+	if svc.User == "" {
+		for _, p := range append([]string{a.Paths.RootDir, a.Paths.ConfigPath}, logs...) {
+			if err := os.Chown(p, 0, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Chmod(a.Paths.ConfigPath, 0600); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return os.Chmod(a.Paths.RootDir, 0700)
+	}
+
+	u, err := system.LookupUser(svc.User)
+	if err != nil {
+		return err
+	}
+	if err := os.Chown(a.Paths.RootDir, 0, u.GID); err != nil {
+		return err
+	}
+	if err := os.Chmod(a.Paths.RootDir, 0750); err != nil {
+		return err
+	}
+	if err := os.Chown(a.Paths.ConfigPath, 0, u.GID); err != nil {
+		return err
+	}
+	if err := os.Chmod(a.Paths.ConfigPath, 0640); err != nil {
+		return err
+	}
+	for _, p := range logs {
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return err
+		}
+		_ = f.Close()
+		if err := os.Chown(p, u.UID, u.GID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTraversable 确认数据目录的各级父目录允许其他用户进入，否则服务用户读不到配置。
+func checkTraversable(dir string) error {
+	for d := filepath.Dir(dir); ; d = filepath.Dir(d) {
+		fi, err := os.Stat(d)
+		if err != nil {
+			return err
+		}
+		if fi.Mode().Perm()&0001 == 0 {
+			return fmt.Errorf("目录 %s 不允许其他用户进入，服务用户无法访问数据目录；请通过 ALPINE_VLESS_HOME 把数据目录放到 /var/lib 等位置", d)
+		}
+		if d == "/" {
+			return nil
+		}
+	}
+}
+
+// verifyPrivileges 在服务启动后检查 sing-box 进程确实已切换到服务用户，且有效 capability 不超出预期。
+func (a *App) verifyPrivileges(ctx context.Context, svc state.Service) error {
+	if svc.User == "" {
+		return nil
+	}
+	u, err := system.LookupUser(svc.User)
+	if err != nil {
+		return err
+	}
+	caps, err := singbox.RequiredCapabilities(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+	allowed, err := system.CapabilityMask(caps)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, privilegeCheckTimeout)
+	defer cancel()
+	for {
+		err = a.checkProcess(ctx, u, allowed)
+		if err == nil {
+			fmt.Fprintf(a.Out, "已确认 sing-box 以 %s（uid %d）运行，保留的 capability: %s\n", u.Name, u.UID, orDash(strings.Join(caps, ",")))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("权限检查未通过: %w", err)
+		case <-time.After(healthInterval):
+		}
+	}
+}
+
+func (a *App) checkProcess(ctx context.Context, u system.SystemUser, allowed uint64) error {
+	pid, err := a.initSys.MainPID(ctx, a.Paths.ServiceName)
+	if err != nil {
+		return err
+	}
+	st, err := system.ReadProcStatus(pid)
+	if err != nil {
+		return err
+	}
+	for _, id := range st.UIDs {
+		if id != u.UID {
+			return fmt.Errorf("进程 %d 的 UID 为 %v，未切换到 %s（uid %d）", pid, st.UIDs, u.Name, u.UID)
+		}
+	}
+	if extra := st.CapEff &^ allowed; extra != 0 {
+		return fmt.Errorf("进程 %d 持有多余的 capability（CapEff %016x，允许 %016x）", pid, st.CapEff, allowed)
+	}
+	if st.CapEff != allowed {
+		return fmt.Errorf("进程 %d 缺少所需的 capability（CapEff %016x，需要 %016x），服务管理器可能不支持保留 capability（OpenRC 需 0.45+）", pid, st.CapEff, allowed)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+func (a *App) Status(ctx context.Context) error {
+	st, err := state.Load(a.Paths.StatePath)
+	if err != nil {
+		return err
+	}
+
+	healthErr := a.serviceStatus(ctx)
+
+	fmt.Fprintln(a.Out, "===== sing-box 状态 =====")
+	fmt.Fprintf(a.Out, "二进制路径: %s\n", a.Paths.SingBoxPath)
+
+	info, err := singbox.BinaryVersion(ctx, a.Paths.SingBoxPath)
+	if err != nil {
+		fmt.Fprintf(a.Out, "已安装版本: 未知（%v）\n", err)
+	} else {
+		fmt.Fprintf(a.Out, "已安装版本: %s\n", info.Version)
+		fmt.Fprintf(a.Out, "构建标签: %s\n", orDash(strings.Join(info.Tags, ",")))
+	}
+
+	if sum, err := system.SHA256File(a.Paths.SingBoxPath); err == nil {
+		fmt.Fprintf(a.Out, "二进制 SHA-256: %s\n", sum)
+	}
+	if !st.SingBox.InstalledAt.IsZero() {
+		fmt.Fprintf(a.Out, "安装时间: %s\n", st.SingBox.InstalledAt.Local().Format(time.DateTime))
+	}
+	if st.SingBox.Asset != "" {
+		verified := "未校验"
+		if st.SingBox.Verified {
+			verified = "已校验"
+		}
+		if st.SingBox.SHA256 != "" {
+			fmt.Fprintf(a.Out, "安装包: %s（%s，SHA-256 %s）\n", st.SingBox.Asset, verified, st.SingBox.SHA256)
+		} else {
+			fmt.Fprintf(a.Out, "安装包: %s（%s）\n", st.SingBox.Asset, verified)
+		}
+	}
+
+	source := st.Source
+	if source == "" {
+		source = paths.SourceGitHub
+	}
+	fmt.Fprintf(a.Out, "安装来源: %s\n", source)
+
+	channel, latest, err := a.latestVersion(ctx, st)
+	if channel != "" && channel != paths.SourceAPK {
+		fmt.Fprintf(a.Out, "更新通道: %s\n", channel)
+	}
+	if err != nil {
+		fmt.Fprintf(a.Out, "最新版本: 获取失败（%v）\n", err)
+		return healthErr
+	}
+	fmt.Fprintf(a.Out, "最新版本: %s\n", latest)
+	switch {
+	case info.Version == "":
+		fmt.Fprintln(a.Out, "可用更新: 未知")
+	case singbox.CompareVersions(latest, info.Version) > 0:
+		fmt.Fprintln(a.Out, "可用更新: 是（运行 upgrade 升级）")
+	default:
+		fmt.Fprintln(a.Out, "可用更新: 否")
+	}
+	return healthErr
+}
+
+// serviceStatus 输出服务运行情况；服务未运行、未监听端口或未设置开机自启时返回错误，便于脚本判断。
+func (a *App) serviceStatus(ctx context.Context) error {
+	var problems []string
+	fmt.Fprintln(a.Out, "===== 服务状态 =====")
+	fmt.Fprintf(a.Out, "服务名: %s（数据目录 %s）\n", a.Paths.ServiceName, a.Paths.RootDir)
+
+	status, running, err := a.initSys.Status(ctx, a.Paths.ServiceName)
+	if err != nil {
+		fmt.Fprintf(a.Out, "服务状态: 未知（%v）\n", err)
+		problems = append(problems, "无法获取服务状态")
+	} else {
+		fmt.Fprintf(a.Out, "服务状态: %s\n", status)
+		if !running {
+			problems = append(problems, "服务未运行")
+		}
+	}
+
+	if a.initSys.Enabled(ctx, a.Paths.ServiceName) {
+		fmt.Fprintln(a.Out, "开机自启: 是")
+	} else {
+		fmt.Fprintln(a.Out, "开机自启: 否")
+		problems = append(problems, "未设置开机自启")
+	}
+
+	if pid, err := a.initSys.MainPID(ctx, a.Paths.ServiceName); err != nil {
+		fmt.Fprintf(a.Out, "进程: 未找到（%v）\n", err)
+	} else {
+		fmt.Fprintf(a.Out, "进程: PID %d\n", pid)
+		if up, err := system.ProcUptime(pid); err == nil {
+			fmt.Fprintf(a.Out, "运行时长: %s\n", up)
+		}
+		if ps, err := system.ReadProcStatus(pid); err == nil {
+			fmt.Fprintf(a.Out, "内存占用: %s（RSS）\n", system.FormatBytes(ps.VmRSS))
+		}
+		if n, err := system.OpenFDs(pid); err == nil {
+			fmt.Fprintf(a.Out, "打开的文件描述符: %d\n", n)
+		}
+	}
+
+	if cfg, err := singbox.ReadConfig(a.Paths.ConfigPath); err != nil {
+		fmt.Fprintf(a.Out, "监听端口: 未知（%v）\n", err)
+	} else if ok, err := system.TCPListening(cfg.Node.Port); err != nil {
+		fmt.Fprintf(a.Out, "监听端口: %d（检查失败: %v）\n", cfg.Node.Port, err)
+	} else if ok {
+		fmt.Fprintf(a.Out, "监听端口: %d（已监听）\n", cfg.Node.Port)
+	} else {
+		fmt.Fprintf(a.Out, "监听端口: %d（未监听）\n", cfg.Node.Port)
+		problems = append(problems, fmt.Sprintf("端口 %d 未监听", cfg.Node.Port))
+	}
+
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "运行用户: %s\n", orDash(svc.User))
+	fmt.Fprintf(a.Out, "重启策略: 崩溃 %d 秒后重启，%d 秒内最多 %d 次\n", svc.RespawnDelay, svc.RespawnPeriod, svc.RespawnMax)
+	if l, err := effectiveLimits(svc.Limits); err == nil {
+		fmt.Fprintf(a.Out, "资源限制: %s\n", formatLimits(l))
+	}
+	if n, ok := a.initSys.RespawnCount(ctx, a.Paths.ServiceName); ok {
+		fmt.Fprintf(a.Out, "服务管理: %s（自动重启 %d 次）\n", a.initSys.Name(), n)
+	} else {
+		fmt.Fprintf(a.Out, "服务管理: %s（未运行或无法获取自动重启次数）\n", a.initSys.Name())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("服务异常: %s", strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/singbox"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const (
+	healthTimeout   = 20 * time.Second
+	healthInterval  = time.Second
+	healthSuccesses = 3
+)
+
+func (a *App) Upgrade(ctx context.Context) error {
+	return a.UpgradeWith(ctx, InstallOptions{})
+}
+
+func (a *App) UpgradeWith(ctx context.Context, opts InstallOptions) error {
+	if err := singbox.ValidateChannel(opts.Channel); err != nil {
+		return err
+	}
+	if !a.IsInstalled() {
+		return errors.New("未检测到本工具管理的已部署实例，请先添加配置")
+	}
+
+	cfg, err := singbox.ReadConfig(a.Paths.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	source, err := a.installSource("")
+	if err != nil {
+		return err
+	}
+	if opts.Source != "" {
+		if _, err := a.installSource(opts.Source); err != nil {
+			return err
+		}
+		if opts.Source != source {
+			return errors.New("升级不能切换安装来源，请使用 add --source 重新部署")
+		}
+	}
+
+	defer func() { _ = os.Remove(a.Paths.ConfigPath + ".new") }()
+	if source == paths.SourceAPK {
+		return a.upgradeAPK(ctx, opts, cfg.Node.Port)
+	}
+
+	newPath := a.Paths.SingBoxPath + ".new"
+	defer func() { _ = os.Remove(newPath) }()
+
+	bin, err := a.installSingBox(ctx, opts, newPath)
+	if errors.Is(err, errAlreadyInstalled) {
+		fmt.Fprintf(a.Out, "sing-box 已是 %s，无需升级。\n", bin.Version)
+		if opts.Channel != "" {
+			return a.updateState(func(st *state.State) { st.Channel = opts.Channel })
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.printSelfCheck(bin)
+
+	raw, checkPath, changed, err := a.stageConfig(bin.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := singbox.CheckConfig(ctx, newPath, checkPath); err != nil {
+		return fmt.Errorf("新版本 sing-box 校验配置失败，已放弃升级: %w", err)
+	}
+
+	if err := os.Rename(a.Paths.SingBoxPath, a.Paths.SingBoxPrevPath); err != nil {
+		return err
+	}
+	if err := os.Rename(newPath, a.Paths.SingBoxPath); err != nil {
+		_ = os.Rename(a.Paths.SingBoxPrevPath, a.Paths.SingBoxPath)
+		return err
+	}
+	if changed {
+		if err := a.commitConfig(raw); err != nil {
+			_ = os.Rename(a.Paths.SingBoxPrevPath, a.Paths.SingBoxPath)
+			return err
+		}
+	}
+
+	if err := a.restartHealthy(ctx, cfg.Node.Port); err != nil {
+		if rbErr := a.rollback(ctx, changed); rbErr != nil {
+			return fmt.Errorf("升级失败: %v；回滚也失败: %w", err, rbErr)
+		}
+		return fmt.Errorf("升级失败，已自动回滚到旧版本: %w", err)
+	}
+
+	if err := a.saveBinaryState(bin, opts.Channel); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Out, "已升级 sing-box 到 %s，旧版本保留为 %s。\n", bin.Version, a.Paths.SingBoxPrevPath)
+	return nil
+}
+
+// stageConfig 按目标版本迁移配置：有变更时写入 config.json.new 并返回其路径供校验，否则返回当前配置路径。
+func (a *App) stageConfig(version string) (raw []byte, checkPath string, changed bool, err error) {
+	raw, err = os.ReadFile(a.Paths.ConfigPath)
+	if err != nil {
+		return nil, "", false, err
+	}
+	migrated, changes, err := singbox.MigrateConfig(raw, version)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("迁移配置失败: %w", err)
+	}
+	if len(changes) == 0 {
+		return raw, a.Paths.ConfigPath, false, nil
+	}
+
+	newConfig := a.Paths.ConfigPath + ".new"
+	if err := os.WriteFile(newConfig, migrated, 0600); err != nil {
+		return nil, "", false, err
+	}
+	fmt.Fprintf(a.Out, "为 sing-box %s 迁移配置：\n", version)
+	for _, c := range changes {
+		fmt.Fprintf(a.Out, "  - %s\n", c)
+	}
+	return raw, newConfig, true, nil
+}
+
+// commitConfig 把旧配置保留为 config.json.prev，并启用 stageConfig 生成的新配置。
+func (a *App) commitConfig(raw []byte) error {
+	if err := os.WriteFile(a.Paths.ConfigPrevPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(a.Paths.ConfigPath+".new", a.Paths.ConfigPath)
+}
+
+func (a *App) restartHealthy(ctx context.Context, port int) error {
+	if err := a.restartService(ctx); err != nil {
+		return err
+	}
+	if err := a.waitHealthy(ctx, port); err != nil {
+		return err
+	}
+	svc, err := a.serviceOptions()
+	if err != nil {
+		return err
+	}
+	return a.verifyPrivileges(ctx, svc)
+}
+
+func (a *App) rollback(ctx context.Context, restoreConfig bool) error {
+	if err := a.restoreBinary(); err != nil {
+		return err
+	}
+	if restoreConfig {
+		if err := os.Rename(a.Paths.ConfigPrevPath, a.Paths.ConfigPath); err != nil {
+			return err
+		}
+	}
+	return a.restartService(ctx)
+}
+
+// restoreBinary 用 sing-box.prev 恢复旧版本；apk 安装时二进制不在数据目录，rename 可能跨文件系统，改为复制。
+func (a *App) restoreBinary() error {
+	if !system.FileExists(a.Paths.SingBoxPrevPath) {
+		return errors.New("未找到旧版本 sing-box")
+	}
+	if err := os.Rename(a.Paths.SingBoxPrevPath, a.Paths.SingBoxPath); err == nil {
+		return nil
+	}
+	return system.CopyFile(a.Paths.SingBoxPrevPath, a.Paths.SingBoxPath, 0755)
+}
+
+// waitHealthy 要求服务状态正常且监听端口可连通，并连续保持若干次，避免“启动即崩溃”被误判为成功。
+func (a *App) waitHealthy(ctx context.Context, port int) error {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+
+	var lastErr error
+	ok := 0
+	for {
+		lastErr = a.probe(ctx, port)
+		if lastErr == nil {
+			ok++
+			if ok >= healthSuccesses {
+				return nil
+			}
+		} else {
+			ok = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return fmt.Errorf("健康检查未通过: %w", lastErr)
+		case <-time.After(healthInterval):
+		}
+	}
+}
+
+func (a *App) probe(ctx context.Context, port int) error {
+	status, running, err := a.initSys.Status(ctx, a.Paths.ServiceName)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf("服务未运行（%s）", status)
+	}
+
+	d := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
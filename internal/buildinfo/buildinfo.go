@@ -0,0 +1,48 @@
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// 以下变量在发布构建时通过 -ldflags "-X" 注入，例如：
+//
+//	-ldflags "-X github.com/pkssssss/alpine-vless/internal/buildinfo.Version=v1.2.0"
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+func String() string {
+	commit := Commit
+	if commit == "" {
+		commit = vcsRevision()
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	date := Date
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("alpine-vless %s (commit %s, built %s, %s %s/%s)",
+		Version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func vcsRevision() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" {
+			if len(s.Value) > 12 {
+				return s.Value[:12]
+			}
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package bundle
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	manifestName = "manifest.json"
+	sumsName     = "SHA256SUMS"
+	archiveDir   = "archives/"
+)
+
+// Manifest 描述离线包中的 sing-box 压缩包及其摘要。
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []Entry   `json:"entries"`
+}
+
+type Entry struct {
+	Version string `json:"version"`
+	Asset   string `json:"asset"`
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+}
+
+type File struct {
+	Entry
+	Path string
+}
+
+// Write 生成离线包（未压缩的 tar）：manifest.json、SHA256SUMS 与 archives/<asset>。
+func Write(dest string, files []File) error {
+	m := Manifest{CreatedAt: time.Now().UTC()}
+	var sums strings.Builder
+	for _, f := range files {
+		m.Entries = append(m.Entries, f.Entry)
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Asset)
+	}
+	mb, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	mb = append(mb, '\n')
+
+	tmp := dest + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp) }()
+
+	tw := tar.NewWriter(out)
+	if err := writeBytes(tw, manifestName, mb, m.CreatedAt); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := writeBytes(tw, sumsName, []byte(sums.String()), m.CreatedAt); err != nil {
+		_ = out.Close()
+		return err
+	}
+	for _, f := range files {
+		if err := writeFile(tw, archiveDir+f.Asset, f.Path); err != nil {
+			_ = out.Close()
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+func ReadManifest(bundlePath string) (Manifest, error) {
+	var m Manifest
+	found := false
+	err := walk(bundlePath, func(name string, r io.Reader) (bool, error) {
+		if name != manifestName {
+			return false, nil
+		}
+		found = true
+		return true, json.NewDecoder(r).Decode(&m)
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+	if !found {
+		return Manifest{}, fmt.Errorf("%s 不是有效的离线包：缺少 %s", bundlePath, manifestName)
+	}
+	for _, e := range m.Entries {
+		if !validAssetName(e.Asset) {
+			return Manifest{}, fmt.Errorf("%s 不是有效的离线包：非法的文件名 %q", bundlePath, e.Asset)
+		}
+	}
+	return m, nil
+}
+
+// validAssetName 拒绝带路径的文件名，避免解包时写到缓存目录之外。
+func validAssetName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// Extract 将离线包中的 archives/<asset> 解出到 destPath。
+func Extract(bundlePath, asset, destPath string) error {
+	found := false
+	err := walk(bundlePath, func(name string, r io.Reader) (bool, error) {
+		if name != archiveDir+asset {
+			return false, nil
+		}
+		found = true
+
+		out, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return true, err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			_ = out.Close()
+			return true, err
+		}
+		return true, out.Close()
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("离线包中未找到 %s", asset)
+	}
+	return nil
+}
+
+func walk(bundlePath string, fn func(name string, r io.Reader) (stop bool, err error)) error {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		stop, err := fn(path.Clean(hdr.Name), tr)
+		if err != nil || stop {
+			return err
+		}
+	}
+}
+
+func writeBytes(tw *tar.Writer, name string, b []byte, mod time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: mod,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+func writeFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,52 @@
+package initsys
+
+import (
+	"context"
+
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/state"
+)
+
+// ManagedMarker 标记由本工具生成的服务文件，只有带此标记的文件才会被覆盖或删除。
+const ManagedMarker = "# managed-by: alpine-vless"
+
+// System 为服务管理后端（OpenRC 或 systemd）。
+type System interface {
+	// Name 为后端名称，如 openrc、systemd。
+	Name() string
+
+	// IsInstalled 判断实例的服务是否已由本工具安装。
+	IsInstalled(p paths.Paths) bool
+	// Install 写入实例的服务定义；svc.User 非空时以该用户运行，并只保留 caps 中的 capability。
+	Install(ctx context.Context, p paths.Paths, svc state.Service, caps []string) error
+	// ServiceFile 返回保存实例服务参数的文件，供提示信息使用。
+	ServiceFile(p paths.Paths) string
+	// Remove 停止、取消开机自启并删除实例的服务定义。
+	Remove(ctx context.Context, p paths.Paths) error
+	// Instances 列出本机由本工具管理的实例。
+	Instances() ([]Instance, error)
+
+	EnableAndStart(ctx context.Context, name string) error
+	Restart(ctx context.Context, name string) error
+	// Reload 向 sing-box 发送 SIGHUP 重新加载配置，不重启进程，也不经过服务管理器的重启。
+	Reload(ctx context.Context, name string) error
+
+	// Status 返回服务状态文本，running 表示服务处于运行状态。
+	Status(ctx context.Context, name string) (status string, running bool, err error)
+	// Enabled 判断服务是否设置了开机自启。
+	Enabled(ctx context.Context, name string) bool
+	// MainPID 返回 sing-box 进程的 PID。
+	MainPID(ctx context.Context, name string) (int, error)
+	// MainPIDShell 返回输出 sing-box 进程号的 shell 片段（供 logrotate 等外部脚本使用）。
+	MainPIDShell(name string) string
+	// RespawnCount 返回服务崩溃后自动重启的次数；无法获取时 ok 为 false。
+	RespawnCount(ctx context.Context, name string) (n int, ok bool)
+}
+
+// Instance 为一个由本工具管理的部署。
+type Instance struct {
+	// Name 为实例名，默认实例为空。
+	Name        string
+	ServiceName string
+	Home        string
+}
@@ -0,0 +1,263 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+	LevelPanic
+)
+
+var levelNames = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL", "PANIC"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return "UNKNOWN"
+	}
+	return levelNames[l]
+}
+
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "WARNING" {
+		s = "WARN"
+	}
+	for i, n := range levelNames {
+		if n == s {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的日志级别 %q：可选 trace/debug/info/warn/error/fatal/panic", s)
+}
+
+// Source 为一个日志文件；无法识别级别的行（如 stderr 中的 panic 堆栈）按 Default 处理。
+type Source struct {
+	Name    string
+	Path    string
+	Default Level
+}
+
+type Entry struct {
+	Source string
+	// Time 为行首的时间戳；没有时间戳的行（多行消息的后续行）沿用上一行的时间。
+	Time  time.Time
+	Level Level
+	Line  string
+}
+
+// sing-box 开启 timestamp 后的行首格式，例如 "+0800 2024-05-01 12:34:56 INFO ..."。
+const timeLayout = "-0700 2006-01-02 15:04:05"
+
+func parseLine(src Source, line string, prev Entry) Entry {
+	e := Entry{Source: src.Name, Time: prev.Time, Level: src.Default, Line: line}
+	rest := line
+	if len(line) > len(timeLayout) {
+		if t, err := time.Parse(timeLayout, line[:len(timeLayout)]); err == nil {
+			e.Time = t
+			rest = strings.TrimLeft(line[len(timeLayout):], " ")
+		} else if prev.Line != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			// 缩进的后续行（堆栈等）与上一行同级别。
+			e.Level = prev.Level
+			return e
+		}
+	}
+
+	// 级别可能是 "INFO ..." 或未初始化日志前输出的 "FATAL[0000] ..."。
+	word := rest
+	if i := strings.IndexAny(rest, " ["); i >= 0 {
+		word = rest[:i]
+	}
+	if l, err := ParseLevel(word); err == nil && word != "" {
+		e.Level = l
+	}
+	return e
+}
+
+// Tail 返回各文件中级别不低于 min 的最后 n 条日志，按时间合并排序（n <= 0 表示全部）。
+// 返回的 offsets 为各文件已读取到的位置，可交给 Follow 继续读取。
+func Tail(srcs []Source, n int, min Level) ([]Entry, []int64, error) {
+	var all []Entry
+	offsets := make([]int64, len(srcs))
+	for i, src := range srcs {
+		entries, off, err := tailFile(src, n, min)
+		if err != nil {
+			return nil, nil, err
+		}
+		all = append(all, entries...)
+		offsets[i] = off
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+	if n > 0 && len(all) > n {
+		all = all[len(all)-n:]
+	}
+	return all, offsets, nil
+}
+
+func tailFile(src Source, n int, min Level) ([]Entry, int64, error) {
+	f, err := os.Open(src.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var (
+		ring []Entry
+		prev Entry
+		off  int64
+	)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			// 未写完的最后一行留给 Follow。
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		off += int64(len(line))
+		prev = parseLine(src, strings.TrimRight(line, "\r\n"), prev)
+		if prev.Level >= min {
+			ring = append(ring, prev)
+			if n > 0 && len(ring) > 2*n {
+				ring = append(ring[:0], ring[len(ring)-n:]...)
+			}
+		}
+	}
+	if n > 0 && len(ring) > n {
+		ring = ring[len(ring)-n:]
+	}
+	// 整个文件都没有时间戳（如 stderr）时，用修改时间近似，合并时不至于全部排在最前。
+	if fi, err := f.Stat(); err == nil {
+		for i := range ring {
+			if ring[i].Time.IsZero() {
+				ring[i].Time = fi.ModTime()
+			}
+		}
+	}
+	return ring, off, nil
+}
+
+const pollInterval = 500 * time.Millisecond
+
+// Follow 从 offsets 处开始持续读取新增日志并回调 fn，直到 ctx 取消。
+// 文件被截断或轮转（inode 变化）时从新文件开头读取。
+func Follow(ctx context.Context, srcs []Source, offsets []int64, min Level, fn func(Entry)) error {
+	followers := make([]*follower, len(srcs))
+	for i, src := range srcs {
+		followers[i] = &follower{src: src, off: offsets[i], ino: inode(src.Path)}
+	}
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		var batch []Entry
+		for _, f := range followers {
+			entries, err := f.poll(min)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, entries...)
+		}
+		sort.SliceStable(batch, func(i, j int) bool { return batch[i].Time.Before(batch[j].Time) })
+		for _, e := range batch {
+			fn(e)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+type follower struct {
+	src     Source
+	off     int64
+	ino     uint64
+	partial string
+	prev    Entry
+}
+
+func (f *follower) poll(min Level) ([]Entry, error) {
+	fi, err := os.Stat(f.src.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if ino := fileInode(fi); ino != f.ino || fi.Size() < f.off {
+		f.ino, f.off, f.partial = ino, 0, ""
+	}
+	if fi.Size() == f.off {
+		return nil, nil
+	}
+
+	file, err := os.Open(f.src.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(f.off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	f.off += int64(len(b))
+
+	data := f.partial + string(b)
+	lines := strings.Split(data, "\n")
+	f.partial = lines[len(lines)-1]
+
+	var out []Entry
+	for _, line := range lines[:len(lines)-1] {
+		f.prev = parseLine(f.src, strings.TrimRight(line, "\r"), f.prev)
+		if f.prev.Time.IsZero() {
+			f.prev.Time = time.Now()
+		}
+		if f.prev.Level >= min {
+			out = append(out, f.prev)
+		}
+	}
+	return out, nil
+}
+
+func inode(path string) uint64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fileInode(fi)
+}
+
+func fileInode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return st.Ino
+	}
+	return 0
+}
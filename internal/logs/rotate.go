@@ -0,0 +1,193 @@
+package logs
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RotatePolicy 为轮转策略：文件达到 MaxSize，或距上次轮转超过 Interval 时轮转；保留 Keep 个 gzip 归档。
+type RotatePolicy struct {
+	MaxSize  int64
+	Interval time.Duration
+	Keep     int
+}
+
+// RotateFile 描述一个需要轮转的日志文件。
+type RotateFile struct {
+	Path string
+	// CopyTruncate 为 true 时复制后截断原文件（写入方持有文件描述符且不会重新打开，如 supervise-daemon）；
+	// 否则重命名原文件并调用 Reopen 通知写入方重新打开，rotated 为重命名后的路径。
+	CopyTruncate bool
+	Reopen       func(rotated string) error
+}
+
+// Due 判断文件是否需要轮转；last 为上次轮转时间（零值表示从未轮转，只按大小判断）。
+func (p RotatePolicy) Due(path string, last, now time.Time) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if fi.Size() == 0 {
+		return false, nil
+	}
+	if p.MaxSize > 0 && fi.Size() >= p.MaxSize {
+		return true, nil
+	}
+	return p.Interval > 0 && !last.IsZero() && now.Sub(last) >= p.Interval, nil
+}
+
+// Rotate 把 path 轮转为 path.1.gz，已有归档依次后移，超出 keep 的删除。
+func Rotate(f RotateFile, keep int) error {
+	if keep < 1 {
+		keep = 1
+	}
+	_ = os.Remove(archiveName(f.Path, keep))
+	for i := keep - 1; i >= 1; i-- {
+		if err := os.Rename(archiveName(f.Path, i), archiveName(f.Path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	tmp := f.Path + ".1"
+	if f.CopyTruncate {
+		if err := copyFile(f.Path, tmp); err != nil {
+			return err
+		}
+		if err := os.Truncate(f.Path, 0); err != nil {
+			return err
+		}
+	} else {
+		if err := os.Rename(f.Path, tmp); err != nil {
+			return err
+		}
+		if f.Reopen != nil {
+			if err := f.Reopen(tmp); err != nil {
+				return fmt.Errorf("已轮转 %s，但通知重新打开日志失败: %w", f.Path, err)
+			}
+		}
+	}
+	return gzipFile(tmp, archiveName(f.Path, 1))
+}
+
+func archiveName(path string, i int) string {
+	return fmt.Sprintf("%s.%d.gz", path, i)
+}
+
+// gzipFile 压缩 src 到 dest 后删除 src，归档沿用原文件的权限。
+func gzipFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	gz.Name = strings.TrimSuffix(filepath.Base(dest), ".gz")
+	gz.ModTime = fi.ModTime()
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dest + ".tmp")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dest + ".tmp")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dest + ".tmp")
+		return err
+	}
+	if err := os.Rename(dest+".tmp", dest); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// LogrotateConfig 生成等价的 logrotate 配置（系统安装了 logrotate 时使用）；reopenCmd 在重命名后执行，
+// create 非空时（如 "0640 user group"）重命名后按该属主重新创建文件，供不能在数据目录创建文件的服务用户写入。
+func LogrotateConfig(marker string, files []RotateFile, p RotatePolicy, reopenCmd, create string) string {
+	var renamed, truncated []string
+	for _, f := range files {
+		if f.CopyTruncate {
+			truncated = append(truncated, f.Path)
+		} else {
+			renamed = append(renamed, f.Path)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, marker)
+	block := func(paths []string, extra string) {
+		if len(paths) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s {\n", strings.Join(paths, " "))
+		switch {
+		case p.Interval >= 30*24*time.Hour:
+			b.WriteString("    monthly\n")
+		case p.Interval >= 7*24*time.Hour:
+			b.WriteString("    weekly\n")
+		case p.Interval > 0:
+			b.WriteString("    daily\n")
+		}
+		if p.MaxSize > 0 {
+			// 与时间间隔同时设置时，maxsize 让超出大小的文件提前轮转。
+			key := "size"
+			if p.Interval > 0 {
+				key = "maxsize"
+			}
+			fmt.Fprintf(&b, "    %s %d\n", key, p.MaxSize)
+		}
+		fmt.Fprintf(&b, "    rotate %d\n", p.Keep)
+		b.WriteString("    compress\n    missingok\n    notifempty\n")
+		b.WriteString(extra)
+		b.WriteString("}\n")
+	}
+	extra := fmt.Sprintf("    postrotate\n        %s\n    endscript\n", reopenCmd)
+	if create != "" {
+		extra = fmt.Sprintf("    create %s\n", create) + extra
+	}
+	block(renamed, extra)
+	block(truncated, "    copytruncate\n")
+	return b.String()
+}
@@ -13,6 +13,11 @@ type Handler interface {
 	Show(ctx context.Context) error
 	Uninstall(ctx context.Context) error
 	EnableBBR(ctx context.Context) error
+	Upgrade(ctx context.Context) error
+	Status(ctx context.Context) error
+	Logs(ctx context.Context) error
+	FollowLogs(ctx context.Context) error
+	Rotate(ctx context.Context) error
 }
 
 func Run(ctx context.Context, in *bufio.Reader, out, errOut io.Writer, h Handler) error {
@@ -22,6 +27,11 @@ func Run(ctx context.Context, in *bufio.Reader, out, errOut io.Writer, h Handler
 		fmt.Fprintln(out, "2) 查看配置（输出一键导入 URL）")
 		fmt.Fprintln(out, "3) 删除配置（卸载/清空）")
 		fmt.Fprintln(out, "4) 一键开启 BBR（fq + bbr）")
+		fmt.Fprintln(out, "5) 升级 sing-box（失败自动回滚）")
+		fmt.Fprintln(out, "6) 查看状态（版本/更新/摘要）")
+		fmt.Fprintln(out, "7) 查看日志（最近 50 行，合并三个日志文件）")
+		fmt.Fprintln(out, "8) 跟踪日志（回车返回菜单）")
+		fmt.Fprintln(out, "9) 更换凭据（UUID/Reality 密钥，热重载不重启）")
 		fmt.Fprintln(out, "0) 退出")
 		fmt.Fprint(out, "选择: ")
 
@@ -54,6 +64,26 @@ func Run(ctx context.Context, in *bufio.Reader, out, errOut io.Writer, h Handler
 			if err := h.EnableBBR(ctx); err != nil {
 				fmt.Fprintln(errOut, "错误:", err.Error())
 			}
+		case "5":
+			if err := h.Upgrade(ctx); err != nil {
+				fmt.Fprintln(errOut, "错误:", err.Error())
+			}
+		case "6":
+			if err := h.Status(ctx); err != nil {
+				fmt.Fprintln(errOut, "错误:", err.Error())
+			}
+		case "7":
+			if err := h.Logs(ctx); err != nil {
+				fmt.Fprintln(errOut, "错误:", err.Error())
+			}
+		case "8":
+			if err := followLogs(ctx, in, out, errOut, h); err != nil {
+				return err
+			}
+		case "9":
+			if err := h.Rotate(ctx); err != nil {
+				fmt.Fprintln(errOut, "错误:", err.Error())
+			}
 		case "0":
 			return nil
 		default:
@@ -62,10 +92,34 @@ func Run(ctx context.Context, in *bufio.Reader, out, errOut io.Writer, h Handler
 	}
 }
 
+// followLogs 跟踪日志直到用户按回车；读取回车的 goroutine 结束前不返回，避免它吞掉下一次菜单输入。
+func followLogs(ctx context.Context, in *bufio.Reader, out, errOut io.Writer, h Handler) error {
+	fctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := in.ReadString('\n')
+		cancel()
+		done <- err
+	}()
+
+	if err := h.FollowLogs(fctx); err != nil {
+		fmt.Fprintln(errOut, "错误:", err.Error())
+		fmt.Fprintln(out, "按回车返回菜单")
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func confirmUninstall(in *bufio.Reader, out io.Writer) bool {
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "⚠️ 危险操作检测！")
-	fmt.Fprintln(out, "操作类型：卸载（停止服务、移除 OpenRC、自启配置、删除落地文件）")
+	fmt.Fprintln(out, "操作类型：卸载（停止服务、移除服务与自启配置、删除落地文件）")
 	fmt.Fprintln(out, "影响范围：当前工具管理的 sing-box 相关文件与服务")
 	fmt.Fprintln(out, "风险评估：卸载后代理不可用，需要重新运行并安装")
 	fmt.Fprintln(out)
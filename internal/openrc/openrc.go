@@ -6,21 +6,45 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/pkssssss/alpine-vless/internal/initsys"
 	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/state"
 	"github.com/pkssssss/alpine-vless/internal/system"
 )
 
-const managedMarker = "# managed-by: alpine-vless"
+const managedMarker = initsys.ManagedMarker
+
+// System 为 OpenRC 后端：各实例共用 /etc/init.d/alpine-vless，参数写在 /etc/conf.d/<服务名>，
+// 由 supervise-daemon 监督运行。
+type System struct{}
+
+var _ initsys.System = System{}
+
+func (System) Name() string { return "openrc" }
+
+// optionsDir 为 OpenRC 保存服务运行时数据的目录。
+const optionsDir = "/run/openrc/options"
 
 const (
 	legacyServiceName = "sing-box"
 	legacyServiceFile = "/etc/init.d/sing-box"
 )
 
-func IsManagedServiceFile(serviceFile string) bool {
+// confDKeys 为写入 conf.d 的变量；旧版把它们直接写在 init 脚本中，迁移时按此列表提取。
+var confDKeys = []string{
+	"command", "command_args", "command_user", "capabilities",
+	"respawn_delay", "respawn_max", "respawn_period", "output_log", "error_log",
+}
+
+// homeKey 记录实例的数据目录，供 Instances 列出。
+const homeKey = "alpine_vless_home"
+
+func isManagedFile(serviceFile string) bool {
 	b, err := os.ReadFile(serviceFile)
 	if err != nil {
 		return false
@@ -28,35 +52,267 @@ func IsManagedServiceFile(serviceFile string) bool {
 	return bytes.Contains(b, []byte(managedMarker))
 }
 
-func InstallServiceFile(p paths.Paths) error {
-	if b, err := os.ReadFile(p.ServiceFile); err == nil {
-		if !bytes.Contains(b, []byte(managedMarker)) {
+// IsInstalled 判断实例的服务是否已由本工具安装：init 脚本受管理，且存在该实例的 conf.d
+// （旧版默认实例把参数写在 init 脚本中，没有 conf.d）。
+func (System) IsInstalled(p paths.Paths) bool {
+	if !isManagedFile(p.ServiceFile) {
+		return false
+	}
+	if isManagedFile(p.ConfDFile) {
+		return true
+	}
+	return p.ServiceFile == p.BaseServiceFile && isInlineScript(p.BaseServiceFile)
+}
+
+// Install 写入共用的 init 脚本与该实例的 conf.d（含 svc.Limits 中的资源限制），命名实例再创建指向 init 脚本的符号链接；
+// svc.User 非空时以该用户运行，并只保留 caps 中的 capability。
+func (System) Install(_ context.Context, p paths.Paths, svc state.Service, caps []string) error {
+	for _, f := range []string{p.BaseServiceFile, p.ConfDFile} {
+		if b, err := os.ReadFile(f); err == nil && !bytes.Contains(b, []byte(managedMarker)) {
+			return fmt.Errorf("检测到已有文件 %s，但不是本工具管理，拒绝覆盖", f)
+		}
+	}
+	if p.ServiceFile != p.BaseServiceFile {
+		if fi, err := os.Lstat(p.ServiceFile); err == nil && fi.Mode()&os.ModeSymlink == 0 {
 			return fmt.Errorf("检测到已有服务文件 %s，但不是本工具管理，拒绝覆盖", p.ServiceFile)
 		}
 	}
 
-	pidfile := filepath.Join("/run", p.ServiceName+".pid")
-	content := strings.TrimLeft(fmt.Sprintf(`#!/sbin/openrc-run
-%s
-command="%s"
-command_args="run -c \"%s\""
-command_background=yes
-pidfile="%s"
-output_log="%s"
-error_log="%s"
+	if err := migrateInlineScript(p.BaseServiceFile); err != nil {
+		return err
+	}
+	script := baseScript(system.CommandExists("supervise-daemon"))
+	if err := os.WriteFile(p.BaseServiceFile, []byte(script), 0755); err != nil {
+		return err
+	}
+	if err := os.Chmod(p.BaseServiceFile, 0755); err != nil {
+		return err
+	}
+
+	if err := system.MkdirAll0755(filepath.Dir(p.ConfDFile)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.ConfDFile, []byte(confD(p, svc, caps)), 0644); err != nil {
+		return err
+	}
+
+	if p.ServiceFile != p.BaseServiceFile {
+		_ = os.Remove(p.ServiceFile)
+		return os.Symlink(filepath.Base(p.BaseServiceFile), p.ServiceFile)
+	}
+	return nil
+}
+
+// ServiceFile 返回实例的 conf.d 文件。
+func (System) ServiceFile(p paths.Paths) string {
+	return p.ConfDFile
+}
+
+// baseScript 生成各实例共用的 init 脚本；supervised 为 false（旧版 OpenRC 没有 supervise-daemon）时退回 command_background。
+func baseScript(supervised bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#!/sbin/openrc-run\n%s\n", managedMarker)
+	b.WriteString("# 各实例的参数见 /etc/conf.d/${RC_SVCNAME}\n")
+	if supervised {
+		b.WriteString("supervisor=supervise-daemon\n")
+	} else {
+		b.WriteString("command_background=yes\n")
+	}
+	b.WriteString(`pidfile="/run/${RC_SVCNAME}.pid"
+extra_started_commands="reload"
 
 depend() {
     need net
 }
-`, managedMarker, p.SingBoxPath, p.ConfigPath, pidfile, p.OpenRCOutLogPath, p.OpenRCErrLogPath), "\n")
 
-	if err := os.WriteFile(p.ServiceFile, []byte(content), 0755); err != nil {
+# reload 向 sing-box 发送 SIGHUP，重新加载配置而不重启进程。
+reload() {
+    ebegin "Reloading ${RC_SVCNAME}"
+    if [ "${supervisor}" = "supervise-daemon" ]; then
+        supervise-daemon "${RC_SVCNAME}" --signal HUP
+    else
+        start-stop-daemon --signal HUP --pidfile "${pidfile}"
+    fi
+    eend $?
+}
+`)
+	return b.String()
+}
+
+// confD 生成实例的服务参数。OpenRC 会先加载基础服务的 conf.d，因此每个键都要写出（包括空值），
+// 避免命名实例继承默认实例的 command_user 等设置。
+func confD(p paths.Paths, svc state.Service, caps []string) string {
+	var user, capabilities string
+	if svc.User != "" {
+		user = svc.User
+		if svc.Group != "" {
+			user += ":" + svc.Group
+		}
+		if len(caps) > 0 {
+			// ^ 表示放入 ambient 集合，切换用户后仍然有效（OpenRC 0.45+）。
+			capabilities = "^" + strings.Join(caps, ",^")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", managedMarker)
+	fmt.Fprintf(&b, "%s=\"%s\"\n", homeKey, p.RootDir)
+	fmt.Fprintf(&b, "command=\"%s\"\n", p.SingBoxPath)
+	fmt.Fprintf(&b, "command_args=\"run -c \\\"%s\\\"\"\n", p.ConfigPath)
+	fmt.Fprintf(&b, "command_user=\"%s\"\n", user)
+	fmt.Fprintf(&b, "capabilities=\"%s\"\n", capabilities)
+	fmt.Fprintf(&b, "respawn_delay=%d\n", svc.RespawnDelay)
+	fmt.Fprintf(&b, "respawn_max=%d\n", svc.RespawnMax)
+	fmt.Fprintf(&b, "respawn_period=%d\n", svc.RespawnPeriod)
+	fmt.Fprintf(&b, "output_log=\"%s\"\n", p.OpenRCOutLogPath)
+	fmt.Fprintf(&b, "error_log=\"%s\"\n", p.OpenRCErrLogPath)
+
+	l := svc.Limits
+	nofile := ""
+	if l.NoFile > 0 {
+		nofile = fmt.Sprintf("-n %d", l.NoFile)
+	}
+	fmt.Fprintf(&b, "rc_ulimit=\"%s\"\n", nofile)
+	nice := ""
+	if l.Nice != 0 {
+		nice = strconv.Itoa(l.Nice)
+	}
+	// 环境变量由 supervise-daemon/start-stop-daemon 继承；未设置的显式 unset，避免沿用基础服务 conf.d 中的值。
+	writeEnv(&b, "GOMEMLIMIT", l.MemLimit)
+	writeEnv(&b, "GOGC", l.GOGC)
+	writeEnv(&b, "SSD_NICELEVEL", nice)
+	writeEnv(&b, "SSD_IONICELEVEL", l.IONice)
+	writeEnv(&b, "SSD_OOM_SCORE_ADJ", l.OOMScoreAdj)
+	return b.String()
+}
+
+func writeEnv(b *strings.Builder, key, val string) {
+	if val == "" {
+		fmt.Fprintf(b, "unset %s\n", key)
+		return
+	}
+	fmt.Fprintf(b, "export %s=\"%s\"\n", key, val)
+}
+
+// isInlineScript 判断是否为旧版把参数直接写在脚本中的 init 脚本。
+func isInlineScript(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(b, []byte(managedMarker)) {
+		return false
+	}
+	return !bytes.Contains(b, []byte("${RC_SVCNAME}"))
+}
+
+// migrateInlineScript 把旧版 init 脚本中的参数移到默认实例的 conf.d，之后脚本可被各实例共用。
+func migrateInlineScript(base string) error {
+	if !isInlineScript(base) {
+		return nil
+	}
+	confDFile := filepath.Join("/etc/conf.d", filepath.Base(base))
+	if system.FileExists(confDFile) {
+		return nil
+	}
+	vars, err := readAssignments(base)
+	if err != nil {
 		return err
 	}
-	return os.Chmod(p.ServiceFile, 0755)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", managedMarker)
+	if args, ok := vars["command_args"]; ok {
+		if cfg := configFromArgs(args); cfg != "" {
+			fmt.Fprintf(&b, "%s=\"%s\"\n", homeKey, filepath.Dir(cfg))
+		}
+	}
+	for _, k := range confDKeys {
+		if v, ok := vars[k]; ok {
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+		} else {
+			fmt.Fprintf(&b, "%s=\"\"\n", k)
+		}
+	}
+	if err := system.MkdirAll0755(filepath.Dir(confDFile)); err != nil {
+		return err
+	}
+	return os.WriteFile(confDFile, []byte(b.String()), 0644)
+}
+
+// readAssignments 读取脚本中顶层的 key=value 行，值保持原样（含引号）。
+func readAssignments(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || k == "" || strings.ContainsAny(k, " \t#") {
+			continue
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// configFromArgs 从 command_args（形如 "run -c \"/path/config.json\""）中取出配置文件路径。
+func configFromArgs(args string) string {
+	_, after, ok := strings.Cut(args, "-c ")
+	if !ok {
+		return ""
+	}
+	return strings.Trim(after, `"\ `)
+}
+
+func unquote(v string) string {
+	return strings.Trim(v, `"'`)
+}
+
+// Instances 通过 /etc/conf.d 中带管理标记的文件列出本机的所有实例（含旧版未迁移的默认实例）。
+func (System) Instances() ([]initsys.Instance, error) {
+	entries, err := os.ReadDir("/etc/conf.d")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var out []initsys.Instance
+	seenBase := false
+	for _, e := range entries {
+		name := e.Name()
+		inst, ok := strings.CutPrefix(name, paths.BaseServiceName)
+		if !ok || (inst != "" && !strings.HasPrefix(inst, ".")) {
+			continue
+		}
+		f := filepath.Join("/etc/conf.d", name)
+		if !isManagedFile(f) {
+			continue
+		}
+		vars, err := readAssignments(f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, initsys.Instance{Name: strings.TrimPrefix(inst, "."), ServiceName: name, Home: unquote(vars[homeKey])})
+		seenBase = seenBase || inst == ""
+	}
+
+	base := filepath.Join("/etc/init.d", paths.BaseServiceName)
+	if !seenBase && isInlineScript(base) {
+		vars, err := readAssignments(base)
+		if err != nil {
+			return nil, err
+		}
+		home := ""
+		if cfg := configFromArgs(unquote(vars["command_args"])); cfg != "" {
+			home = filepath.Dir(cfg)
+		}
+		out = append([]initsys.Instance{{ServiceName: paths.BaseServiceName, Home: home}}, out...)
+	}
+	return out, nil
 }
 
-func EnableAndStart(ctx context.Context, serviceName string) error {
+func (System) EnableAndStart(ctx context.Context, serviceName string) error {
+	if err := cleanupLegacy(ctx); err != nil {
+		return err
+	}
 	_ = system.Run(ctx, "rc-update", "add", serviceName, "default")
 	if err := system.Run(ctx, "rc-service", serviceName, "restart"); err == nil {
 		return nil
@@ -64,8 +320,9 @@ func EnableAndStart(ctx context.Context, serviceName string) error {
 	return system.Run(ctx, "rc-service", serviceName, "start")
 }
 
-func CleanupLegacyManaged(ctx context.Context) error {
-	if !system.FileExists(legacyServiceFile) || !IsManagedServiceFile(legacyServiceFile) {
+// cleanupLegacy 移除早期版本以 sing-box 为服务名安装的服务。
+func cleanupLegacy(ctx context.Context) error {
+	if !system.FileExists(legacyServiceFile) || !isManagedFile(legacyServiceFile) {
 		return nil
 	}
 	_ = system.Run(ctx, "rc-service", legacyServiceName, "stop")
@@ -74,15 +331,91 @@ func CleanupLegacyManaged(ctx context.Context) error {
 	return nil
 }
 
-func StopDisableAndRemove(ctx context.Context, p paths.Paths) error {
-	if system.FileExists(p.ServiceFile) && !IsManagedServiceFile(p.ServiceFile) {
+// Remove 停止并移除实例的服务；共用的 init 脚本在没有其他实例使用时才删除。
+func (s System) Remove(ctx context.Context, p paths.Paths) error {
+	if system.FileExists(p.ServiceFile) && !isManagedFile(p.ServiceFile) {
 		return errors.New("检测到非本工具管理的 OpenRC 服务文件，拒绝卸载")
 	}
 
 	_ = system.Run(ctx, "rc-service", p.ServiceName, "stop")
 	_ = system.Run(ctx, "rc-update", "del", p.ServiceName, "default")
-	_ = os.Remove(p.ServiceFile)
+	if isManagedFile(p.ConfDFile) {
+		_ = os.Remove(p.ConfDFile)
+	}
+	if p.ServiceFile != p.BaseServiceFile {
+		_ = os.Remove(p.ServiceFile)
+	}
 
-	_ = CleanupLegacyManaged(ctx)
+	rest, err := s.Instances()
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 && isManagedFile(p.BaseServiceFile) {
+		_ = os.Remove(p.BaseServiceFile)
+	}
+
+	_ = cleanupLegacy(ctx)
 	return nil
 }
+
+func (System) Restart(ctx context.Context, serviceName string) error {
+	return system.Run(ctx, "rc-service", serviceName, "restart")
+}
+
+// Reload 通过 init 脚本的 reload 命令让 sing-box 重新加载配置；旧版脚本没有 reload 时返回错误。
+func (System) Reload(ctx context.Context, serviceName string) error {
+	return system.Run(ctx, "rc-service", serviceName, "reload")
+}
+
+// RespawnCount 返回 supervise-daemon 记录的自动重启次数（/run/openrc/options/<服务名>/start_count）；
+// 服务未由 supervise-daemon 运行时 ok 为 false。
+func (System) RespawnCount(_ context.Context, serviceName string) (n int, ok bool) {
+	b, err := os.ReadFile(filepath.Join(optionsDir, serviceName, "start_count"))
+	if err != nil {
+		return 0, false
+	}
+	n, err = strconv.Atoi(strings.TrimSpace(string(b)))
+	return n, err == nil
+}
+
+// MainPID 返回 sing-box 进程的 PID：supervise-daemon 记录在 child_pid 中，否则 pidfile 即为该进程。
+func (System) MainPID(_ context.Context, serviceName string) (int, error) {
+	b, err := os.ReadFile(filepath.Join(optionsDir, serviceName, "child_pid"))
+	if err != nil {
+		b, err = os.ReadFile(pidfile(serviceName))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("无法确定 %s 的进程号: %w", serviceName, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+func pidfile(serviceName string) string {
+	return filepath.Join("/run", serviceName+".pid")
+}
+
+// Status 返回 rc-service status 报告的状态（如 started、stopped、crashed）。
+func (System) Status(ctx context.Context, serviceName string) (string, bool, error) {
+	// 服务未运行时 rc-service 以非零状态退出，但输出中仍包含状态，因此不用 system.Output。
+	out, err := exec.CommandContext(ctx, "rc-service", serviceName, "status").CombinedOutput()
+	if _, after, ok := strings.Cut(string(out), "status:"); ok {
+		status := strings.TrimSpace(after)
+		return status, status == "started", nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("rc-service %s status 失败: %w: %s", serviceName, err, out)
+	}
+	return strings.TrimSpace(string(out)), false, nil
+}
+
+// Enabled 判断服务是否已加入 default 运行级别（/etc/runlevels/default/<服务名>）。
+func (System) Enabled(_ context.Context, serviceName string) bool {
+	_, err := os.Lstat(filepath.Join("/etc/runlevels", "default", serviceName))
+	return err == nil
+}
+
+// MainPIDShell 返回输出 sing-box 进程号的 shell 片段，与 MainPID 的查找顺序一致（供 logrotate 等外部脚本使用）。
+func (System) MainPIDShell(serviceName string) string {
+	return fmt.Sprintf("cat %s 2>/dev/null || cat %s 2>/dev/null",
+		filepath.Join(optionsDir, serviceName, "child_pid"), pidfile(serviceName))
+}
@@ -5,23 +5,67 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 )
 
+// 安装来源：github 为官方 release 压缩包（默认），apk 为 Alpine community 仓库的 sing-box 包。
+const (
+	SourceGitHub = "github"
+	SourceAPK    = "apk"
+)
+
+// apkSingBoxPath 为 apk 安装的 sing-box 所在位置。
+const apkSingBoxPath = "/usr/bin/sing-box"
+
+// BaseServiceName 为默认实例的服务名；命名实例为 alpine-vless.<实例名>（OpenRC multiservice 或同名 systemd unit）。
+const BaseServiceName = "alpine-vless"
+
+var instanceNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidateInstance 校验实例名：小写字母、数字、- 与 _，不能含 .（OpenRC 以 . 分隔基础服务名）。
+func ValidateInstance(name string) error {
+	if !instanceNameRe.MatchString(name) {
+		return fmt.Errorf("非法的实例名 %q：只能包含小写字母、数字、- 与 _，且以字母或数字开头", name)
+	}
+	return nil
+}
+
 type Paths struct {
-	RootDir string
+	// Instance 为实例名，默认实例为空。
+	Instance string
+	RootDir  string
 
-	SingBoxPath string
-	ConfigPath  string
-	LogPath     string
+	SingBoxPath      string
+	SingBoxPrevPath  string
+	ConfigPath       string
+	ConfigPrevPath   string
+	RunConfigPath    string
+	StatePath        string
+	VersionCachePath string
+	LogPath          string
+	CacheDir         string
+	UpdateLogPath    string
 
 	OpenRCOutLogPath string
 	OpenRCErrLogPath string
 
 	ServiceName string
-	ServiceFile string
+	// ServiceFile 为该实例的 init 脚本：默认实例即 BaseServiceFile，命名实例为指向它的符号链接。
+	ServiceFile     string
+	BaseServiceFile string
+	// ConfDFile 保存该实例的服务参数（command、command_user、respawn_* 等）。
+	ConfDFile string
 }
 
-func Discover() (Paths, error) {
+// Discover 计算实例的路径：数据目录默认为 <二进制所在目录>/alpine-vless-data[.<实例名>]，
+// 也可由 ALPINE_VLESS_HOME 指定。
+func Discover(instance string) (Paths, error) {
+	if instance != "" {
+		if err := ValidateInstance(instance); err != nil {
+			return Paths{}, err
+		}
+	}
+
 	if v := os.Getenv("ALPINE_VLESS_HOME"); v != "" {
 		abs, err := filepath.Abs(v)
 		if err != nil {
@@ -31,19 +75,7 @@ func Discover() (Paths, error) {
 		if rootDir == "" || rootDir == "/" || rootDir == "." {
 			return Paths{}, errors.New("ALPINE_VLESS_HOME 非法：禁止为根目录或当前目录")
 		}
-		return Paths{
-			RootDir: rootDir,
-
-			SingBoxPath: filepath.Join(rootDir, "sing-box"),
-			ConfigPath:  filepath.Join(rootDir, "config.json"),
-			LogPath:     filepath.Join(rootDir, "sing-box.log"),
-
-			OpenRCOutLogPath: filepath.Join(rootDir, "openrc.out.log"),
-			OpenRCErrLogPath: filepath.Join(rootDir, "openrc.err.log"),
-
-			ServiceName: "alpine-vless",
-			ServiceFile: "/etc/init.d/alpine-vless",
-		}, nil
+		return fromRoot(rootDir, instance), nil
 	}
 
 	exe, err := os.Executable()
@@ -56,18 +88,54 @@ func Discover() (Paths, error) {
 		return Paths{}, fmt.Errorf("无法确定可写入的运行目录: %q", exeDir)
 	}
 
-	rootDir := filepath.Join(exeDir, "alpine-vless-data")
+	dataDir := "alpine-vless-data"
+	if instance != "" {
+		dataDir += "." + instance
+	}
+	return fromRoot(filepath.Join(exeDir, dataDir), instance), nil
+}
+
+// At 返回数据目录为 rootDir 的实例的路径，用于访问本机的其他实例。
+func At(rootDir, instance string) Paths {
+	return fromRoot(rootDir, instance)
+}
+
+func fromRoot(rootDir, instance string) Paths {
+	service := BaseServiceName
+	if instance != "" {
+		service += "." + instance
+	}
 	return Paths{
-		RootDir: rootDir,
+		Instance: instance,
+		RootDir:  rootDir,
 
-		SingBoxPath: filepath.Join(rootDir, "sing-box"),
-		ConfigPath:  filepath.Join(rootDir, "config.json"),
-		LogPath:     filepath.Join(rootDir, "sing-box.log"),
+		SingBoxPath:      filepath.Join(rootDir, "sing-box"),
+		SingBoxPrevPath:  filepath.Join(rootDir, "sing-box.prev"),
+		ConfigPath:       filepath.Join(rootDir, "config.json"),
+		ConfigPrevPath:   filepath.Join(rootDir, "config.json.prev"),
+		RunConfigPath:    filepath.Join(rootDir, "config.run.json"),
+		StatePath:        filepath.Join(rootDir, "state.json"),
+		VersionCachePath: filepath.Join(rootDir, "latest-version.json"),
+		LogPath:          filepath.Join(rootDir, "sing-box.log"),
+		CacheDir:         filepath.Join(rootDir, "cache"),
+		UpdateLogPath:    filepath.Join(rootDir, "update-history.log"),
 
 		OpenRCOutLogPath: filepath.Join(rootDir, "openrc.out.log"),
 		OpenRCErrLogPath: filepath.Join(rootDir, "openrc.err.log"),
 
-		ServiceName: "alpine-vless",
-		ServiceFile: "/etc/init.d/alpine-vless",
-	}, nil
+		ServiceName:     service,
+		ServiceFile:     filepath.Join("/etc/init.d", service),
+		BaseServiceFile: filepath.Join("/etc/init.d", BaseServiceName),
+		ConfDFile:       filepath.Join("/etc/conf.d", service),
+	}
+}
+
+// ForSource 返回按安装来源调整后的路径：apk 安装时 sing-box 位于 /usr/bin，其余文件仍在数据目录。
+func (p Paths) ForSource(source string) Paths {
+	if source == SourceAPK {
+		p.SingBoxPath = apkSingBoxPath
+	} else {
+		p.SingBoxPath = filepath.Join(p.RootDir, "sing-box")
+	}
+	return p
 }
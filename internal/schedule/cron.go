@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const managedMarker = "# managed-by: alpine-vless"
+
+// crontabDirs 为各发行版存放用户 crontab 的目录：busybox crond（Alpine）、Debian cron、cronie（RHEL 系）。
+var crontabDirs = []string{"/etc/crontabs", "/var/spool/cron/crontabs", "/var/spool/cron"}
+
+// crontabFile 返回 root 的 crontab 路径，按 crontabDirs 的顺序取第一个存在的目录。
+func crontabFile() string {
+	for _, d := range crontabDirs {
+		if fi, err := os.Stat(d); err == nil && fi.IsDir() {
+			return filepath.Join(d, "root")
+		}
+	}
+	return filepath.Join(crontabDirs[0], "root")
+}
+
+// errNoCron 表示本机没有可用的 cron 服务，写入的 crontab 不会被执行。
+var errNoCron = errors.New("未检测到 cron 服务，定时任务不会执行；请先安装 cron（Alpine: apk add busybox-openrc，Debian: apt install cron，RHEL 系: dnf install cronie）")
+
+// Install 确保 cron 服务已启用后，在 root 的 crontab 中写入（或替换）一条以 id 标识的任务。
+func Install(ctx context.Context, id, spec, command string) error {
+	if err := enableCron(ctx); err != nil {
+		return err
+	}
+
+	lines, err := readCrontab()
+	if err != nil {
+		return err
+	}
+	lines = removeJob(lines, id)
+	lines = append(lines, fmt.Sprintf("%s %s %s %s", spec, command, managedMarker, id))
+	return writeCrontab(lines)
+}
+
+// enableCron 启用并启动 cron 服务；找不到 cron 服务时返回 errNoCron。
+func enableCron(ctx context.Context) error {
+	switch {
+	case system.CommandExists("rc-service"):
+		if !system.FileExists("/etc/init.d/crond") {
+			return errNoCron
+		}
+		_ = system.Run(ctx, "rc-update", "add", "crond", "default")
+		_ = system.Run(ctx, "rc-service", "crond", "start")
+		return nil
+	case system.CommandExists("systemctl"):
+		// Debian 的服务名为 cron，RHEL 系为 crond，Arch 为 cronie。
+		for _, unit := range []string{"cron", "crond", "cronie"} {
+			if system.Run(ctx, "systemctl", "enable", "--now", unit) == nil {
+				return nil
+			}
+		}
+		return errNoCron
+	default:
+		// 没有服务管理器（如容器）时无法确认 crond 在运行，至少要求已安装。
+		if system.CommandExists("crond") || system.CommandExists("cron") {
+			return nil
+		}
+		return errNoCron
+	}
+}
+
+// Remove 删除以 id 标识的任务；不存在时不报错。
+func Remove(id string) error {
+	lines, err := readCrontab()
+	if err != nil {
+		return err
+	}
+	kept := removeJob(lines, id)
+	if len(kept) == len(lines) {
+		return nil
+	}
+	return writeCrontab(kept)
+}
+
+func removeJob(lines []string, id string) []string {
+	suffix := managedMarker + " " + id
+	var out []string
+	for _, l := range lines {
+		if strings.HasSuffix(strings.TrimSpace(l), suffix) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func readCrontab() ([]string, error) {
+	b, err := os.ReadFile(crontabFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	s := strings.TrimRight(string(b), "\n")
+	if s == "" {
+		return nil, nil
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+// writeCrontab 通过临时文件 + rename 写入，目录 mtime 变化会让 crond 重新加载。
+func writeCrontab(lines []string) error {
+	crontabFile := crontabFile()
+	if err := os.MkdirAll(filepath.Dir(crontabFile), 0755); err != nil {
+		return err
+	}
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	tmp := crontabFile + ".alpine-vless.tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, crontabFile)
+}
+
+// ShellQuote 用单引号包裹参数，供拼接 crontab 命令行使用。
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,61 @@
+package selfupdate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+// Executable 返回当前程序（解析符号链接后）的路径。
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// Replace 把 src 复制为目标目录内的临时文件，确认其可以运行后再 rename，原子替换 dest；src 本身不做修改。
+func Replace(ctx context.Context, src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := filepath.Join(filepath.Dir(dest), "."+filepath.Base(dest)+".new")
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	if err := system.Run(ctx, tmp, "version"); err != nil {
+		return fmt.Errorf("新版本无法运行，已放弃替换: %w", err)
+	}
+	return os.Rename(tmp, dest)
+}
+
+// Exec 以新的二进制替换当前进程。
+func Exec(path string, args ...string) error {
+	return syscall.Exec(path, append([]string{path}, args...), os.Environ())
+}
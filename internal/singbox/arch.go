@@ -0,0 +1,154 @@
+package singbox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+const (
+	atHWCap = 16
+
+	hwcapVFP   = 1 << 6
+	hwcapVFPv3 = 1 << 13
+)
+
+// DetectArch 返回本机可用的 sing-box 架构名，按优先级从高到低排列。
+func DetectArch(goarch string) ([]string, error) {
+	switch goarch {
+	case "amd64":
+		if amd64Level() >= 3 {
+			return []string{"amd64v3", "amd64"}, nil
+		}
+		return []string{"amd64"}, nil
+	case "arm":
+		switch armLevel() {
+		case 7:
+			return []string{"armv7", "armv6", "armv5"}, nil
+		case 6:
+			return []string{"armv6", "armv5"}, nil
+		default:
+			return []string{"armv5"}, nil
+		}
+	case "mipsle":
+		// 上游只提供区分浮点 ABI 的构建；软浮点构建在有 FPU 的机器上同样可用。
+		if mipsHasFPU() {
+			return []string{"mipsle-hardfloat", "mipsle-softfloat"}, nil
+		}
+		return []string{"mipsle-softfloat"}, nil
+	case "386", "arm64", "s390x", "riscv64", "ppc64le", "loong64", "mips64le":
+		return []string{goarch}, nil
+	default:
+		return nil, fmt.Errorf("未支持的架构: %s", goarch)
+	}
+}
+
+// amd64Level 根据 /proc/cpuinfo 的 flags 判断 x86-64 微架构等级（1~3）。
+func amd64Level() int {
+	flags := cpuinfoField("flags")
+	has := func(names ...string) bool {
+		for _, n := range names {
+			if !containsWord(flags, n) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !has("cx16", "lahf_lm", "popcnt", "sse4_1", "sse4_2", "ssse3") {
+		return 1
+	}
+	if !has("avx", "avx2", "bmi1", "bmi2", "f16c", "fma", "abm", "movbe", "xsave") {
+		return 2
+	}
+	return 3
+}
+
+// armLevel 推断 GOARM 等级：ARMv7 且支持 VFPv3 为 7，支持 VFP 为 6，否则为 5。
+// 部分 ARMv6 芯片（如 BCM2835）的 "CPU architecture" 也报告 7，因此必须结合 VFPv3 判断。
+func armLevel() int {
+	cpuArch, _ := strconv.Atoi(strings.TrimRightFunc(cpuinfoField("CPU architecture"), func(r rune) bool {
+		return r < '0' || r > '9'
+	}))
+
+	vfp, vfpv3 := false, false
+	if hwcap, ok := auxvValue(atHWCap); ok {
+		vfp = hwcap&hwcapVFP != 0
+		vfpv3 = hwcap&hwcapVFPv3 != 0
+	} else {
+		features := cpuinfoField("Features")
+		vfp = containsWord(features, "vfp")
+		vfpv3 = containsWord(features, "vfpv3")
+	}
+
+	switch {
+	case cpuArch >= 7 && vfpv3:
+		return 7
+	case vfp:
+		return 6
+	default:
+		return 5
+	}
+}
+
+// mipsHasFPU 判断 MIPS CPU 是否带硬件 FPU：内核会在 /proc/cpuinfo 的 "cpu model" 后附加 "FPU Vx.y"。
+func mipsHasFPU() bool {
+	return strings.Contains(cpuinfoField("cpu model"), "FPU")
+}
+
+func cpuinfoField(name string) string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(k) == name {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// auxvValue 从 /proc/self/auxv 读取指定类型的值（条目为本机字长的 key/value 对）。
+func auxvValue(key uint64) (uint64, bool) {
+	b, err := os.ReadFile("/proc/self/auxv")
+	if err != nil {
+		return 0, false
+	}
+
+	word := int(unsafe.Sizeof(uintptr(0)))
+	read := func(p []byte) uint64 {
+		if word == 4 {
+			return uint64(binary.LittleEndian.Uint32(p))
+		}
+		return binary.LittleEndian.Uint64(p)
+	}
+
+	for i := 0; i+2*word <= len(b); i += 2 * word {
+		k := read(b[i:])
+		if k == 0 {
+			break
+		}
+		if k == key {
+			return read(b[i+word:]), true
+		}
+	}
+	return 0, false
+}
+
+func containsWord(s, word string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package singbox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+type Platform struct {
+	OS string
+	// Archs 为可用的架构名，按优先级从高到低排列（如 amd64v3、amd64）。
+	Archs []string
+	Libc  string
+}
+
+func DetectPlatform(goarch string) (Platform, error) {
+	archs, err := DetectArch(goarch)
+	if err != nil {
+		return Platform{}, err
+	}
+	return Platform{OS: "linux", Archs: archs, Libc: system.Libc()}, nil
+}
+
+func (p Platform) Supports(arch string) bool {
+	for _, a := range p.Archs {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// asset 变体的优先级，数值越小越优先；未列出的变体（如 with-xxx）一律不选。
+const (
+	rankLibc = iota
+	rankGeneric
+	rankLegacy
+)
+
+// SelectAsset 按以下规则从 release 的 asset 列表中选择安装包：
+//   - 仅考虑 sing-box-<version>-<os>-<arch>[-<variant>].tar.gz；
+//   - os 与 arch 必须完全一致（amd64v3 不会被当作 amd64），按 Platform.Archs 的顺序依次尝试；
+//   - variant 为 musl/glibc 时必须与本机 libc 一致，且优先于通用构建；
+//   - legacy 构建只在没有其他可选时使用。
+func SelectAsset(rel Release, p Platform) (Asset, error) {
+	for _, arch := range p.Archs {
+		if a, ok := selectAssetForArch(rel, p, arch); ok {
+			return a, nil
+		}
+	}
+	return Asset{}, fmt.Errorf("release %s 中没有适用于 %s/%s（%s）的安装包", rel.Version, p.OS, strings.Join(p.Archs, "|"), p.Libc)
+}
+
+func selectAssetForArch(rel Release, p Platform, want string) (Asset, bool) {
+	prefix := fmt.Sprintf("sing-box-%s-%s-", rel.Version, p.OS)
+
+	best, bestRank := Asset{}, -1
+	for _, a := range rel.Assets {
+		rest, ok := strings.CutPrefix(a.Name, prefix)
+		if !ok {
+			continue
+		}
+		rest, ok = strings.CutSuffix(rest, ".tar.gz")
+		if !ok {
+			continue
+		}
+		arch, variant := splitArch(rest)
+		if arch != want {
+			continue
+		}
+
+		rank := -1
+		switch {
+		case variant == "":
+			rank = rankGeneric
+		case variant == p.Libc:
+			rank = rankLibc
+		case strings.HasPrefix(variant, "legacy"):
+			rank = rankLegacy
+		}
+		if rank < 0 {
+			continue
+		}
+		if bestRank < 0 || rank < bestRank {
+			best, bestRank = a, rank
+		}
+	}
+
+	return best, bestRank >= 0
+}
+
+// splitArch 把 asset 名中 <arch>[-<variant>] 部分拆开；mips 系列的浮点 ABI（hardfloat/softfloat）属于架构名。
+func splitArch(s string) (arch, variant string) {
+	arch, variant, _ = strings.Cut(s, "-")
+	abi, rest, _ := strings.Cut(variant, "-")
+	if abi == "hardfloat" || abi == "softfloat" {
+		return arch + "-" + abi, rest
+	}
+	return arch, variant
+}
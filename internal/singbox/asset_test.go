@@ -0,0 +1,137 @@
+package singbox
+
+import "testing"
+
+// upstreamAssets 节选自 SagerNet/sing-box v1.10.1 release 的 asset 列表。
+var upstreamAssets = []string{
+	"SHA256SUMS",
+	"sing-box-1.10.1-android-386.tar.gz",
+	"sing-box-1.10.1-android-amd64.tar.gz",
+	"sing-box-1.10.1-android-arm64.tar.gz",
+	"sing-box-1.10.1-android-armv7.tar.gz",
+	"sing-box-1.10.1-darwin-amd64.tar.gz",
+	"sing-box-1.10.1-darwin-arm64.tar.gz",
+	"sing-box-1.10.1-freebsd-386.tar.gz",
+	"sing-box-1.10.1-freebsd-amd64.tar.gz",
+	"sing-box-1.10.1-freebsd-arm64.tar.gz",
+	"sing-box-1.10.1-linux-386.tar.gz",
+	"sing-box-1.10.1-linux-amd64.tar.gz",
+	"sing-box-1.10.1-linux-amd64v3.tar.gz",
+	"sing-box-1.10.1-linux-arm64.tar.gz",
+	"sing-box-1.10.1-linux-armv5.tar.gz",
+	"sing-box-1.10.1-linux-armv6.tar.gz",
+	"sing-box-1.10.1-linux-armv7.tar.gz",
+	"sing-box-1.10.1-linux-loong64.tar.gz",
+	"sing-box-1.10.1-linux-mips-softfloat.tar.gz",
+	"sing-box-1.10.1-linux-mips64.tar.gz",
+	"sing-box-1.10.1-linux-mips64le.tar.gz",
+	"sing-box-1.10.1-linux-mipsle-hardfloat.tar.gz",
+	"sing-box-1.10.1-linux-mipsle-softfloat.tar.gz",
+	"sing-box-1.10.1-linux-ppc64le.tar.gz",
+	"sing-box-1.10.1-linux-riscv64.tar.gz",
+	"sing-box-1.10.1-linux-s390x.tar.gz",
+	"sing-box-1.10.1-windows-386.zip",
+	"sing-box-1.10.1-windows-amd64-legacy.zip",
+	"sing-box-1.10.1-windows-amd64.zip",
+	"sing-box-1.10.1-windows-amd64v3.zip",
+	"sing-box-1.10.1-windows-arm64.zip",
+	"sing-box_1.10.1_linux_amd64.deb",
+	"sing-box_1.10.1_linux_amd64.pkg.tar.zst",
+	"sing-box_1.10.1_linux_amd64.rpm",
+}
+
+func release(version string, names []string) Release {
+	rel := Release{Version: version}
+	for _, n := range names {
+		rel.Assets = append(rel.Assets, Asset{Name: n})
+	}
+	return rel
+}
+
+func TestSelectAsset(t *testing.T) {
+	rel := release("1.10.1", upstreamAssets)
+	tests := []struct {
+		name  string
+		archs []string
+		libc  string
+		want  string
+	}{
+		{"amd64v3", []string{"amd64v3", "amd64"}, "musl", "sing-box-1.10.1-linux-amd64v3.tar.gz"},
+		{"amd64 不会选到 amd64v3", []string{"amd64"}, "glibc", "sing-box-1.10.1-linux-amd64.tar.gz"},
+		{"386", []string{"386"}, "musl", "sing-box-1.10.1-linux-386.tar.gz"},
+		{"armv7", []string{"armv7", "armv6", "armv5"}, "musl", "sing-box-1.10.1-linux-armv7.tar.gz"},
+		{"armv6", []string{"armv6", "armv5"}, "musl", "sing-box-1.10.1-linux-armv6.tar.gz"},
+		{"mips64le 不会选到 mips64", []string{"mips64le"}, "musl", "sing-box-1.10.1-linux-mips64le.tar.gz"},
+		{"mipsle 有 FPU", []string{"mipsle-hardfloat", "mipsle-softfloat"}, "musl", "sing-box-1.10.1-linux-mipsle-hardfloat.tar.gz"},
+		{"mipsle 无 FPU", []string{"mipsle-softfloat"}, "musl", "sing-box-1.10.1-linux-mipsle-softfloat.tar.gz"},
+		{"riscv64", []string{"riscv64"}, "glibc", "sing-box-1.10.1-linux-riscv64.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectAsset(rel, Platform{OS: "linux", Archs: tt.archs, Libc: tt.libc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("选择了 %s，期望 %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+// TestSelectAssetVariants 使用构造的列表覆盖 libc、legacy 与 with-xxx 变体。
+func TestSelectAssetVariants(t *testing.T) {
+	rel := release("1.12.0", []string{
+		"sing-box-1.12.0-linux-amd64.tar.gz",
+		"sing-box-1.12.0-linux-amd64-glibc.tar.gz",
+		"sing-box-1.12.0-linux-amd64-musl.tar.gz",
+		"sing-box-1.12.0-linux-386-legacy-go123.tar.gz",
+		"sing-box-1.12.0-linux-arm64-with-naive.tar.gz",
+	})
+	tests := []struct {
+		name  string
+		archs []string
+		libc  string
+		want  string
+	}{
+		{"musl 优先于通用构建", []string{"amd64"}, "musl", "sing-box-1.12.0-linux-amd64-musl.tar.gz"},
+		{"glibc 优先于通用构建", []string{"amd64"}, "glibc", "sing-box-1.12.0-linux-amd64-glibc.tar.gz"},
+		{"没有其他构建时使用 legacy", []string{"386"}, "musl", "sing-box-1.12.0-linux-386-legacy-go123.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectAsset(rel, Platform{OS: "linux", Archs: tt.archs, Libc: tt.libc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("选择了 %s，期望 %s", got.Name, tt.want)
+			}
+		})
+	}
+
+	if got, err := SelectAsset(rel, Platform{OS: "linux", Archs: []string{"arm64"}, Libc: "musl"}); err == nil {
+		t.Errorf("with-xxx 变体不应被选中，实际选择了 %s", got.Name)
+	}
+}
+
+func TestParseAssetName(t *testing.T) {
+	tests := []struct {
+		name          string
+		version, arch string
+		ok            bool
+	}{
+		{"sing-box-1.10.1-linux-amd64.tar.gz", "1.10.1", "amd64", true},
+		{"sing-box-1.12.0-linux-amd64-musl.tar.gz", "1.12.0", "amd64", true},
+		{"sing-box-1.10.1-linux-mipsle-softfloat.tar.gz", "1.10.1", "mipsle-softfloat", true},
+		{"/tmp/sing-box-1.10.1-linux-mipsle-hardfloat.tar.gz", "1.10.1", "mipsle-hardfloat", true},
+		{"sing-box-1.11.0-beta.1-linux-arm64.tar.gz", "1.11.0-beta.1", "arm64", true},
+		{"sing-box_1.10.1_linux_amd64.deb", "", "", false},
+	}
+	for _, tt := range tests {
+		v, arch, ok := ParseAssetName(tt.name)
+		if v != tt.version || arch != tt.arch || ok != tt.ok {
+			t.Errorf("ParseAssetName(%q) = %q, %q, %v，期望 %q, %q, %v", tt.name, v, arch, ok, tt.version, tt.arch, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package singbox
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveCache 保存校验通过的安装包（<asset> 与 <asset>.sha256），供重装与回滚离线使用。
+type ArchiveCache struct {
+	Dir  string
+	Keep int
+}
+
+// Lookup 在缓存中按版本与平台选择安装包，返回其路径与记录的 SHA-256。
+func (c ArchiveCache) Lookup(version string, p Platform) (Asset, string, bool) {
+	if c.Dir == "" {
+		return Asset{}, "", false
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return Asset{}, "", false
+	}
+
+	rel := Release{Version: version}
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".tar.gz") {
+			rel.Assets = append(rel.Assets, Asset{Name: e.Name()})
+		}
+	}
+	a, err := SelectAsset(rel, p)
+	if err != nil {
+		return Asset{}, "", false
+	}
+
+	b, err := os.ReadFile(filepath.Join(c.Dir, a.Name+".sha256"))
+	if err != nil {
+		return Asset{}, "", false
+	}
+	sum, err := normalizeSHA256(string(b))
+	if err != nil {
+		return Asset{}, "", false
+	}
+	a.SHA256 = sum
+	return a, filepath.Join(c.Dir, a.Name), true
+}
+
+// Store 将已校验的压缩包移动进缓存，并按修改时间只保留最近 Keep 个。
+func (c ArchiveCache) Store(srcPath, assetName, sha256 string) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	dest := filepath.Join(c.Dir, assetName)
+	if err := os.Rename(srcPath, dest); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dest+".sha256", []byte(sha256+"\n"), 0600); err != nil {
+		return err
+	}
+	c.prune()
+	return nil
+}
+
+func (c ArchiveCache) prune() {
+	if c.Keep <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return
+	}
+
+	type item struct {
+		name string
+		mod  int64
+	}
+	var items []item
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ".tar.gz") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, item{e.Name(), info.ModTime().UnixNano()})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].mod > items[j].mod })
+
+	for _, it := range items[min(c.Keep, len(items)):] {
+		_ = os.Remove(filepath.Join(c.Dir, it.name))
+		_ = os.Remove(filepath.Join(c.Dir, it.name+".sha256"))
+	}
+}
@@ -0,0 +1,64 @@
+package singbox
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// 以非 root 用户运行时可能需要保留的 capability（OpenRC capabilities= 的写法）。
+const (
+	CapNetBindService = "cap_net_bind_service"
+	CapNetAdmin       = "cap_net_admin"
+)
+
+// RequiredCapabilities 根据配置推断 sing-box 需要的 capability：监听 1024 以下端口需要 cap_net_bind_service；
+// tun/redirect/tproxy 入站以及 routing_mark、自动检测出口网卡需要 cap_net_admin。
+func RequiredCapabilities(configPath string) ([]string, error) {
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Inbounds []struct {
+			Type       string `json:"type"`
+			ListenPort int    `json:"listen_port"`
+		} `json:"inbounds"`
+		Outbounds []struct {
+			RoutingMark any `json:"routing_mark"`
+		} `json:"outbounds"`
+		Route struct {
+			DefaultMark         any  `json:"default_mark"`
+			AutoDetectInterface bool `json:"auto_detect_interface"`
+		} `json:"route"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, in := range cfg.Inbounds {
+		if in.ListenPort > 0 && in.ListenPort < 1024 {
+			set[CapNetBindService] = true
+		}
+		switch in.Type {
+		case "tun", "redirect", "tproxy":
+			set[CapNetAdmin] = true
+		}
+	}
+	for _, out := range cfg.Outbounds {
+		if out.RoutingMark != nil {
+			set[CapNetAdmin] = true
+		}
+	}
+	if cfg.Route.DefaultMark != nil || cfg.Route.AutoDetectInterface {
+		set[CapNetAdmin] = true
+	}
+
+	caps := make([]string, 0, len(set))
+	for c := range set {
+		caps = append(caps, c)
+	}
+	sort.Strings(caps)
+	return caps, nil
+}
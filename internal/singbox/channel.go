@@ -0,0 +1,50 @@
+package singbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 更新通道：stable 为正式版，beta 含预发布版本，"X.Y"（如 1.10）固定在该次版本线的最新正式版。
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+func ValidateChannel(ch string) error {
+	switch ch {
+	case "", ChannelStable, ChannelBeta:
+		return nil
+	}
+	major, minor, ok := strings.Cut(ch, ".")
+	if ok {
+		if _, err := strconv.Atoi(major); err == nil {
+			if _, err := strconv.Atoi(minor); err == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("非法的更新通道 %q：可选 stable、beta 或固定次版本（如 1.10）", ch)
+}
+
+func pickChannel(rels []Release, channel string) (Release, bool) {
+	var best Release
+	for _, r := range rels {
+		switch channel {
+		case ChannelBeta:
+		case ChannelStable:
+			if r.Prerelease {
+				continue
+			}
+		default:
+			if r.Prerelease || !strings.HasPrefix(r.Version, channel+".") {
+				continue
+			}
+		}
+		if best.Version == "" || CompareVersions(r.Version, best.Version) > 0 {
+			best = r
+		}
+	}
+	return best, best.Version != ""
+}
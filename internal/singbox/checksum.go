@@ -0,0 +1,55 @@
+package singbox
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadChecksumFile 解析 sha256sum 格式的校验文件（"<hex>  <文件名>"），
+// 也接受只有一行摘要的文件。
+func ReadChecksumFile(path, assetName string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var lines [][]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		lines = append(lines, fields)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	for _, fields := range lines {
+		if len(fields) < 2 {
+			continue
+		}
+		name := filepath.Base(strings.TrimPrefix(fields[len(fields)-1], "*"))
+		if name == assetName {
+			return normalizeSHA256(fields[0])
+		}
+	}
+	if len(lines) == 1 && len(lines[0]) == 1 {
+		return normalizeSHA256(lines[0][0])
+	}
+	return "", fmt.Errorf("校验文件 %s 中未找到 %s 的摘要", path, assetName)
+}
+
+func normalizeSHA256(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if b, err := hex.DecodeString(s); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("非法的 SHA-256 摘要: %q", s)
+	}
+	return s, nil
+}
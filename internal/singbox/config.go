@@ -72,6 +72,24 @@ func NewDefaultNode(_ context.Context) (Node, error) {
 	}, nil
 }
 
+// RotateCredentials 保留端口与握手参数，重新生成 UUID、Reality 密钥对与 short_id。
+func (n Node) RotateCredentials() (Node, error) {
+	uuid, err := newUUIDv4()
+	if err != nil {
+		return Node{}, err
+	}
+	sid, err := newShortID()
+	if err != nil {
+		return Node{}, err
+	}
+	priv, _, err := newRealityKeyPair()
+	if err != nil {
+		return Node{}, err
+	}
+	n.UUID, n.RealityShortID, n.RealityPrivateKey = uuid, sid, priv
+	return n, nil
+}
+
 func (n Node) URL(ip, publicKey string) string {
 	host := ip
 	if host == "" {
@@ -98,7 +116,15 @@ func (n Node) URL(ip, publicKey string) string {
 	return u.String()
 }
 
-func WriteConfig(path, logPath string, node Node) error {
+// WriteConfig 按目标 sing-box 版本生成配置；version 为空时按最新写法生成。
+func WriteConfig(path, logPath string, node Node, version string) error {
+	outbounds := []any{
+		map[string]any{"type": "direct", "tag": "direct"},
+	}
+	if !supports(version, versionRuleActions) {
+		outbounds = append(outbounds, map[string]any{"type": "block", "tag": "block"})
+	}
+
 	cfg := map[string]any{
 		"log": map[string]any{
 			"level":     "info",
@@ -132,10 +158,7 @@ func WriteConfig(path, logPath string, node Node) error {
 				},
 			},
 		},
-		"outbounds": []any{
-			map[string]any{"type": "direct", "tag": "direct"},
-			map[string]any{"type": "block", "tag": "block"},
-		},
+		"outbounds": outbounds,
 	}
 
 	b, err := json.MarshalIndent(cfg, "", "  ")
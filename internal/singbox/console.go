@@ -0,0 +1,21 @@
+package singbox
+
+import (
+	"encoding/json"
+)
+
+// WithConsoleLog 返回去掉 log.output 的配置副本，sing-box 随之把日志写到终端而不是文件。
+func WithConsoleLog(raw []byte) ([]byte, error) {
+	var cfg map[string]any
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	if log, ok := cfg["log"].(map[string]any); ok {
+		delete(log, "output")
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
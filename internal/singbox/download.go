@@ -0,0 +1,276 @@
+package singbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const (
+	downloadChunkSize   = 8 << 20
+	downloadMaxAttempts = 8
+	downloadStall       = 30 * time.Second
+	backoffBase         = time.Second
+	backoffMax          = 30 * time.Second
+	progressInterval    = 500 * time.Millisecond
+)
+
+// errPermanent 标记不应重试的下载错误（如 404）。
+type errPermanent struct{ error }
+
+func (e errPermanent) Unwrap() error { return e.error }
+
+// downloadResumable 以 Range 分块下载到 partPath，失败按指数退避重试，并从已有分片继续。
+// 仅在连续 downloadStall 内没有任何数据时才判定超时，不受 http.Client 整体超时限制。
+func downloadResumable(ctx context.Context, httpClient *http.Client, url, partPath string, size int64, progress io.Writer) error {
+	c := *httpClient
+	c.Timeout = 0
+
+	p := newProgress(progress, size)
+	defer p.finish()
+
+	var lastErr error
+	for attempt := 0; attempt < downloadMaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := backoffBase << (attempt - 1)
+			if delay > backoffMax {
+				delay = backoffMax
+			}
+			p.note(fmt.Sprintf("下载中断（%v），%s 后重试（%d/%d）", lastErr, delay, attempt, downloadMaxAttempts-1))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		done, err := downloadChunks(ctx, &c, url, partPath, p)
+		if err == nil && done {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var perm errPermanent
+		if errors.As(err, &perm) {
+			return perm.error
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("下载失败（已重试 %d 次）: %s: %w", downloadMaxAttempts-1, url, lastErr)
+}
+
+// downloadChunks 从 partPath 的当前长度开始逐块下载，直到文件完整或出错。
+func downloadChunks(ctx context.Context, c *http.Client, url, partPath string, p *progress) (bool, error) {
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	for {
+		off, err := f.Seek(0, io.SeekEnd)
+		if err != nil {
+			return false, err
+		}
+		if p.total > 0 && off >= p.total {
+			return true, nil
+		}
+		p.set(off)
+
+		total, full, err := fetchChunk(ctx, c, url, f, off, p)
+		if err != nil {
+			return false, err
+		}
+		if total > 0 {
+			p.total = total
+		}
+		if full || (p.total > 0 && p.done >= p.total) {
+			return true, nil
+		}
+	}
+}
+
+// fetchChunk 请求 [off, off+downloadChunkSize) 并追加到 f。
+// 服务器不支持 Range（返回 200）时会截断文件并一次性下载完整内容，此时 full 为 true。
+func fetchChunk(ctx context.Context, c *http.Client, url string, f *os.File, off int64, p *progress) (total int64, full bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("User-Agent", "alpine-vless-installer")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+downloadChunkSize-1))
+
+	stall := time.AfterFunc(downloadStall, cancel)
+	defer stall.Stop()
+
+	resp, err := c.Do(req)
+	if err != nil {
+		if !stall.Stop() {
+			return 0, false, fmt.Errorf("连接在 %s 内无响应", downloadStall)
+		}
+		return 0, false, wrapHTTPDoError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		total = parseContentRangeTotal(resp.Header.Get("Content-Range"))
+	case resp.StatusCode == http.StatusOK:
+		if err := f.Truncate(0); err != nil {
+			return 0, false, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, false, err
+		}
+		p.set(0)
+		full = true
+		total = resp.ContentLength
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		total = parseContentRangeTotal(resp.Header.Get("Content-Range"))
+		if total > 0 && off >= total {
+			return total, true, nil
+		}
+		if err := f.Truncate(0); err != nil {
+			return 0, false, err
+		}
+		return 0, false, fmt.Errorf("续传位置无效（HTTP %d），已重新开始", resp.StatusCode)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		return 0, false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	default:
+		return 0, false, errPermanent{fmt.Errorf("下载失败: %s (HTTP %d)", url, resp.StatusCode)}
+	}
+	if total > 0 {
+		p.total = total
+	}
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			stall.Reset(downloadStall)
+			if _, err := f.Write(buf[:n]); err != nil {
+				return 0, false, err
+			}
+			p.add(int64(n))
+		}
+		if errors.Is(rerr, io.EOF) {
+			return total, full, nil
+		}
+		if rerr != nil {
+			if ctx.Err() != nil && !stall.Stop() {
+				return 0, false, fmt.Errorf("连续 %s 未收到数据", downloadStall)
+			}
+			return 0, false, rerr
+		}
+	}
+}
+
+func parseContentRangeTotal(v string) int64 {
+	_, totalStr, ok := strings.Cut(v, "/")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(totalStr), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// progress 以单行（\r 覆盖）输出已下载量、速率与剩余时间。
+type progress struct {
+	w     io.Writer
+	total int64
+	done  int64
+
+	mu        sync.Mutex
+	start     time.Time
+	startDone int64
+	last      time.Time
+	printed   bool
+}
+
+func newProgress(w io.Writer, total int64) *progress {
+	return &progress{w: w, total: total, start: time.Now()}
+}
+
+func (p *progress) set(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = n
+	p.start = time.Now()
+	p.startDone = n
+}
+
+func (p *progress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	if time.Since(p.last) >= progressInterval {
+		p.render()
+	}
+}
+
+func (p *progress) note(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.w == nil {
+		return
+	}
+	if p.printed {
+		fmt.Fprintln(p.w)
+		p.printed = false
+	}
+	fmt.Fprintln(p.w, msg)
+}
+
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.w == nil {
+		return
+	}
+	p.render()
+	fmt.Fprintln(p.w)
+}
+
+func (p *progress) render() {
+	if p.w == nil {
+		return
+	}
+	p.last = time.Now()
+	p.printed = true
+
+	elapsed := time.Since(p.start).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(p.done-p.startDone) / elapsed
+	}
+
+	line := "下载中 " + system.FormatBytes(p.done)
+	if p.total > 0 {
+		line += fmt.Sprintf(" / %s (%.1f%%)", system.FormatBytes(p.total), float64(p.done)*100/float64(p.total))
+	}
+	line += fmt.Sprintf("  %s/s", system.FormatBytes(int64(rate)))
+	if p.total > 0 && rate > 0 && p.done < p.total {
+		eta := time.Duration(float64(p.total-p.done)/rate) * time.Second
+		line += "  剩余 " + eta.Round(time.Second).String()
+	}
+	fmt.Fprintf(p.w, "\r%-72s", line)
+}
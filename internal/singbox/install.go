@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -16,99 +17,157 @@ import (
 )
 
 type InstallSpec struct {
+	Source   Source
 	Version  string
-	Arch     string
+	Asset    string
 	DestPath string
+
+	// SHA256 为压缩包的期望摘要（十六进制）；为空时不校验。
+	SHA256 string
+	// Size 为压缩包的期望大小（来自 release 元数据），未知时为 0。
+	Size int64
+
+	// DownloadDir 保存未完成的下载（<asset>.part），中断后可续传；为空时使用系统临时目录。
+	DownloadDir string
+	Progress    io.Writer
+
+	// Cache 非空时，校验通过的压缩包会保存进缓存。
+	Cache *ArchiveCache
+}
+
+func AssetName(version, arch string) string {
+	return fmt.Sprintf("sing-box-%s-linux-%s.tar.gz", version, arch)
 }
 
-func Install(ctx context.Context, httpClient *http.Client, spec InstallSpec) error {
-	if spec.Version == "" || spec.Arch == "" || spec.DestPath == "" {
-		return errors.New("安装参数不完整")
+// ParseAssetName 从官方 asset 文件名（sing-box-<version>-linux-<arch>[-<variant>].tar.gz）中解析版本与架构。
+func ParseAssetName(name string) (version, arch string, ok bool) {
+	name = strings.TrimSuffix(filepath.Base(name), ".tar.gz")
+	rest, found := strings.CutPrefix(name, "sing-box-")
+	if !found {
+		return "", "", false
 	}
+	version, arch, found = strings.Cut(rest, "-linux-")
+	if !found || version == "" || arch == "" {
+		return "", "", false
+	}
+	arch, _ = splitArch(arch)
+	return version, arch, true
+}
+
+// Installed 描述一次安装的结果：压缩包摘要与新二进制自报的版本信息。
+type Installed struct {
+	SHA256 string
+	Info   BinaryInfo
+}
 
-	url := fmt.Sprintf(
-		"https://github.com/SagerNet/sing-box/releases/download/v%s/sing-box-%s-linux-%s.tar.gz",
-		spec.Version, spec.Version, spec.Arch,
-	)
+// Install 下载并解压 sing-box。
+func Install(ctx context.Context, httpClient *http.Client, spec InstallSpec) (Installed, error) {
+	if spec.DestPath == "" {
+		return Installed{}, errors.New("安装参数不完整")
+	}
 
-	tmp, err := os.CreateTemp("", "sing-box-*.tar.gz")
+	partPath, sum, err := Download(ctx, httpClient, spec)
 	if err != nil {
-		return err
+		return Installed{}, err
 	}
-	tmpPath := tmp.Name()
-	defer func() { _ = os.Remove(tmpPath) }()
 
-	if err := downloadToFile(ctx, httpClient, url, tmp); err != nil {
-		_ = tmp.Close()
-		return err
+	info, err := extractSingBoxBinary(ctx, partPath, spec.DestPath, spec.Version)
+	if err == nil && spec.SHA256 != "" && spec.Cache != nil {
+		if cerr := spec.Cache.Store(partPath, spec.Asset, sum); cerr == nil {
+			return Installed{SHA256: sum, Info: info}, nil
+		}
 	}
-	if err := tmp.Close(); err != nil {
-		return err
+	_ = os.Remove(partPath)
+	if err != nil {
+		return Installed{}, err
 	}
+	return Installed{SHA256: sum, Info: info}, nil
+}
 
-	if err := extractSingBoxBinary(tmpPath, spec.Version, spec.Arch, spec.DestPath); err != nil {
-		return err
+// Download 下载压缩包到 DownloadDir/<asset>.part 并校验摘要，返回文件路径与实际摘要；
+// 文件由调用方负责移走或删除。
+func Download(ctx context.Context, httpClient *http.Client, spec InstallSpec) (string, string, error) {
+	if spec.Version == "" || spec.Asset == "" {
+		return "", "", errors.New("下载参数不完整")
 	}
-	return nil
-}
 
-func CheckConfig(ctx context.Context, singBoxPath, configPath string) error {
-	return system.Run(ctx, singBoxPath, "check", "-c", configPath)
-}
+	url := spec.Source.downloadURL(spec.Version, spec.Asset)
 
-func DetectArch(goarch string) (string, error) {
-	switch goarch {
-	case "amd64":
-		return "amd64", nil
-	case "arm64":
-		return "arm64", nil
-	default:
-		return "", fmt.Errorf("未支持的架构: %s", goarch)
+	dir := spec.DownloadDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := system.MkdirAll0700(dir); err != nil {
+		return "", "", err
+	}
+	partPath := filepath.Join(dir, spec.Asset+".part")
+
+	if err := downloadResumable(ctx, httpClient, url, partPath, spec.Size, spec.Progress); err != nil {
+		return "", "", err
 	}
-}
 
-func downloadToFile(ctx context.Context, httpClient *http.Client, url string, f *os.File) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	sum, err := verifyArchive(partPath, spec.SHA256)
 	if err != nil {
-		return err
+		// 校验失败时删除，避免下次续传到损坏的分片上。
+		_ = os.Remove(partPath)
+		return "", "", err
 	}
-	req.Header.Set("User-Agent", "alpine-vless-installer")
+	return partPath, sum, nil
+}
 
-	resp, err := httpClient.Do(req)
+// InstallArchive 从本地压缩包安装 sing-box（不访问网络）。
+func InstallArchive(ctx context.Context, archivePath string, spec InstallSpec) (Installed, error) {
+	if spec.DestPath == "" {
+		return Installed{}, errors.New("安装参数不完整")
+	}
+
+	sum, err := verifyArchive(archivePath, spec.SHA256)
 	if err != nil {
-		return wrapHTTPDoError(err)
+		return Installed{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("下载失败: %s (HTTP %d)", url, resp.StatusCode)
+	info, err := extractSingBoxBinary(ctx, archivePath, spec.DestPath, spec.Version)
+	if err != nil {
+		return Installed{}, err
 	}
+	return Installed{SHA256: sum, Info: info}, nil
+}
 
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		return err
+func verifyArchive(path, want string) (string, error) {
+	sum, err := system.SHA256File(path)
+	if err != nil {
+		return "", err
 	}
-	return nil
+	if want != "" && !strings.EqualFold(sum, want) {
+		return "", fmt.Errorf("sing-box 压缩包校验失败，拒绝安装：期望 SHA-256 %s，实际 %s", want, sum)
+	}
+	return sum, nil
+}
+
+func CheckConfig(ctx context.Context, singBoxPath, configPath string) error {
+	return system.Run(ctx, singBoxPath, "check", "-c", configPath)
 }
 
-func extractSingBoxBinary(tarGzPath, version, arch, destPath string) error {
+// extractSingBoxBinary 解压压缩包中名为 sing-box 的可执行文件，不依赖压缩包内的目录命名。
+// 新文件先经 checkBinary 校验，通过后才替换 destPath；失败时保留原有文件。
+func extractSingBoxBinary(ctx context.Context, tarGzPath, destPath, wantVersion string) (BinaryInfo, error) {
 	f, err := os.Open(tarGzPath)
 	if err != nil {
-		return err
+		return BinaryInfo{}, err
 	}
 	defer f.Close()
 
 	gz, err := gzip.NewReader(f)
 	if err != nil {
-		return err
+		return BinaryInfo{}, err
 	}
 	defer gz.Close()
 
 	tr := tar.NewReader(gz)
-	wantSuffix := fmt.Sprintf("sing-box-%s-linux-%s/sing-box", version, arch)
 
 	destDir := filepath.Dir(destPath)
 	if err := system.MkdirAll0755(destDir); err != nil {
-		return err
+		return BinaryInfo{}, err
 	}
 
 	tmpDest := destPath + ".tmp"
@@ -118,31 +177,39 @@ func extractSingBoxBinary(tarGzPath, version, arch, destPath string) error {
 			break
 		}
 		if err != nil {
-			return err
+			return BinaryInfo{}, err
 		}
 		if hdr.Typeflag != tar.TypeReg {
 			continue
 		}
-		if !strings.HasSuffix(hdr.Name, wantSuffix) {
+		if path.Base(hdr.Name) != "sing-box" {
 			continue
 		}
 
 		out, err := os.OpenFile(tmpDest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
 		if err != nil {
-			return err
+			return BinaryInfo{}, err
 		}
 		if _, err := io.Copy(out, tr); err != nil {
 			_ = out.Close()
-			return err
+			_ = os.Remove(tmpDest)
+			return BinaryInfo{}, err
 		}
 		if err := out.Close(); err != nil {
-			return err
+			_ = os.Remove(tmpDest)
+			return BinaryInfo{}, err
+		}
+
+		info, err := checkBinary(ctx, tmpDest, wantVersion)
+		if err != nil {
+			_ = os.Remove(tmpDest)
+			return info, err
 		}
 		if err := os.Rename(tmpDest, destPath); err != nil {
-			return err
+			return BinaryInfo{}, err
 		}
-		return os.Chmod(destPath, 0755)
+		return info, os.Chmod(destPath, 0755)
 	}
 
-	return errors.New("在压缩包中未找到 sing-box 可执行文件")
+	return BinaryInfo{}, errors.New("在压缩包中未找到 sing-box 可执行文件")
 }
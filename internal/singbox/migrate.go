@@ -0,0 +1,340 @@
+package singbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 以下版本起对应的旧写法被弃用，迁移会在目标版本达到该版本时执行。
+const (
+	// 1.11 起：block/dns 特殊出站与入站的 sniff/domain_strategy 等字段由路由规则动作替代。
+	versionRuleActions = "1.11.0"
+	// 1.12 起：DNS 服务器改为带 type 的新格式，旧的 address 写法被弃用。
+	versionTypedDNS = "1.12.0"
+)
+
+var legacyInboundFields = []string{
+	"sniff",
+	"sniff_override_destination",
+	"sniff_timeout",
+	"domain_strategy",
+	"udp_disable_domain_unmapping",
+}
+
+type migration struct {
+	since string
+	apply func(cfg map[string]any) ([]string, error)
+}
+
+var migrations = []migration{
+	{versionRuleActions, migrateSpecialOutbounds},
+	{versionRuleActions, migrateLegacyInboundFields},
+	{versionTypedDNS, migrateDNSServers},
+}
+
+// legacyRcodes 为旧写法 rcode://<名称> 对应的 DNS 响应码。
+var legacyRcodes = map[string]string{
+	"success":         "NOERROR",
+	"format_error":    "FORMERR",
+	"server_failure":  "SERVFAIL",
+	"name_error":      "NXDOMAIN",
+	"not_implemented": "NOTIMP",
+	"refused":         "REFUSED",
+}
+
+// supports 判断 target 版本是否已包含 since 引入的新写法；target 为空视为最新版本。
+func supports(target, since string) bool {
+	return target == "" || CompareVersions(target, since) >= 0
+}
+
+// MigrateConfig 将现有 config.json 改写为 target 版本可接受的写法，返回新内容与变更说明；
+// 无需变更时 changes 为空，raw 原样返回。
+func MigrateConfig(raw []byte, target string) ([]byte, []string, error) {
+	var cfg map[string]any
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, nil, err
+	}
+
+	var changes []string
+	for _, m := range migrations {
+		if !supports(target, m.since) {
+			continue
+		}
+		c, err := m.apply(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, c...)
+	}
+	if len(changes) == 0 {
+		return raw, nil, nil
+	}
+
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(b, '\n'), changes, nil
+}
+
+// migrateSpecialOutbounds 移除 block/dns 出站，并把引用它们的路由规则改为 reject/hijack-dns 动作；
+// route.final 指向它们时改为末尾一条只有动作的规则，保持“其余流量”的处理不变。
+func migrateSpecialOutbounds(cfg map[string]any) ([]string, error) {
+	outbounds, _ := cfg["outbounds"].([]any)
+	actions := map[string]string{}
+	var kept []any
+	var changes []string
+	for _, o := range outbounds {
+		m, _ := o.(map[string]any)
+		typ, _ := m["type"].(string)
+		tag, _ := m["tag"].(string)
+		switch typ {
+		case "block":
+			actions[tag] = "reject"
+		case "dns":
+			actions[tag] = "hijack-dns"
+		default:
+			kept = append(kept, o)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("移除 %s 出站 %q（改用路由规则动作 %s）", typ, tag, actions[tag]))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	cfg["outbounds"] = kept
+
+	route, _ := cfg["route"].(map[string]any)
+	if route == nil {
+		return changes, nil
+	}
+	rules, _ := route["rules"].([]any)
+	rewriteRules(rules, "outbound", func(tag string) map[string]any {
+		if action, ok := actions[tag]; ok {
+			return map[string]any{"action": action}
+		}
+		return nil
+	})
+	if final, _ := route["final"].(string); final != "" {
+		if action, ok := actions[final]; ok {
+			delete(route, "final")
+			route["rules"] = append(rules, map[string]any{"action": action})
+			changes = append(changes, fmt.Sprintf("route.final %q 改为末尾的 %s 规则", final, action))
+		}
+	}
+	return changes, nil
+}
+
+// rewriteRules 把 key 字段（outbound 或 server）指向已删除对象的规则改为 replace 返回的动作字段；
+// logical 规则的子规则不带动作，只删除该字段。
+func rewriteRules(rules []any, key string, replace func(tag string) map[string]any) {
+	for _, r := range rules {
+		m, _ := r.(map[string]any)
+		if m == nil {
+			continue
+		}
+		if typ, _ := m["type"].(string); typ == "logical" {
+			sub, _ := m["rules"].([]any)
+			for _, s := range sub {
+				if sm, _ := s.(map[string]any); sm != nil {
+					if tag, _ := sm[key].(string); replace(tag) != nil {
+						delete(sm, key)
+					}
+				}
+			}
+		}
+		tag, _ := m[key].(string)
+		if fields := replace(tag); fields != nil {
+			delete(m, key)
+			for k, v := range fields {
+				m[k] = v
+			}
+		}
+	}
+}
+
+// migrateLegacyInboundFields 把入站上的 sniff/domain_strategy/udp_disable_domain_unmapping 改写为路由规则的
+// sniff/resolve/route-options 动作。sniff_override_destination 没有等价的动作，开启时保留该入站的 sniff 旧字段。
+func migrateLegacyInboundFields(cfg map[string]any) ([]string, error) {
+	inbounds, _ := cfg["inbounds"].([]any)
+	var added []any
+	var changes []string
+	for _, in := range inbounds {
+		m, _ := in.(map[string]any)
+		if m == nil {
+			continue
+		}
+		tag, _ := m["tag"].(string)
+
+		found := false
+		for _, f := range legacyInboundFields {
+			if _, ok := m[f]; ok {
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+
+		keepSniff, _ := m["sniff_override_destination"].(bool)
+		if sniff, _ := m["sniff"].(bool); sniff && !keepSniff {
+			rule := map[string]any{"inbound": []any{tag}, "action": "sniff"}
+			if t, ok := m["sniff_timeout"]; ok {
+				rule["timeout"] = t
+			}
+			added = append(added, rule)
+		}
+		if ds, _ := m["domain_strategy"].(string); ds != "" {
+			added = append(added, map[string]any{"inbound": []any{tag}, "action": "resolve", "strategy": ds})
+		}
+		if v, _ := m["udp_disable_domain_unmapping"].(bool); v {
+			added = append(added, map[string]any{"inbound": []any{tag}, "action": "route-options", "udp_disable_domain_unmapping": true})
+		}
+		for _, f := range legacyInboundFields {
+			if keepSniff && (f == "sniff" || f == "sniff_timeout" || f == "sniff_override_destination") {
+				continue
+			}
+			delete(m, f)
+		}
+		if keepSniff {
+			changes = append(changes, fmt.Sprintf("入站 %q 的 domain_strategy 等字段改为路由规则动作；sniff_override_destination 没有等价动作，保留 sniff 旧字段", tag))
+		} else {
+			changes = append(changes, fmt.Sprintf("入站 %q 的 sniff/domain_strategy 等字段改为路由规则动作", tag))
+		}
+	}
+	if len(added) > 0 {
+		route, _ := cfg["route"].(map[string]any)
+		if route == nil {
+			route = map[string]any{}
+			cfg["route"] = route
+		}
+		rules, _ := route["rules"].([]any)
+		route["rules"] = append(added, rules...)
+	}
+	return changes, nil
+}
+
+// migrateDNSServers 把 {"address": "tls://1.1.1.1"} 形式的 DNS 服务器改写为 {"type": "tls", "server": "1.1.1.1"}。
+// rcode:// 服务器没有对应的新类型：删除服务器，引用它的 DNS 规则（及 dns.final）改为 predefined 动作；
+// fakeip 服务器从 dns.fakeip 取得地址范围。
+func migrateDNSServers(cfg map[string]any) ([]string, error) {
+	dns, _ := cfg["dns"].(map[string]any)
+	servers, _ := dns["servers"].([]any)
+	legacyFakeIP, _ := dns["fakeip"].(map[string]any)
+	rcodes := map[string]string{}
+	kept := []any{}
+	var changes []string
+	for _, s := range servers {
+		m, _ := s.(map[string]any)
+		addr, ok := m["address"].(string)
+		if !ok {
+			kept = append(kept, s)
+			continue
+		}
+		tag, _ := m["tag"].(string)
+		if name, ok := strings.CutPrefix(addr, "rcode://"); ok {
+			rcode, ok := legacyRcodes[name]
+			if !ok {
+				return nil, fmt.Errorf("DNS 服务器 %q 使用了未知的 rcode %q", tag, name)
+			}
+			rcodes[tag] = rcode
+			changes = append(changes, fmt.Sprintf("移除 DNS 服务器 %q（改用 DNS 规则动作 predefined %s）", tag, rcode))
+			continue
+		}
+
+		delete(m, "address")
+		for k, v := range parseLegacyDNSAddress(addr) {
+			m[k] = v
+		}
+		if r, ok := m["address_resolver"]; ok {
+			delete(m, "address_resolver")
+			m["domain_resolver"] = r
+		}
+		if m["type"] == "fakeip" {
+			for _, k := range []string{"inet4_range", "inet6_range"} {
+				if v, ok := legacyFakeIP[k]; ok {
+					m[k] = v
+				}
+			}
+		}
+		kept = append(kept, m)
+		changes = append(changes, fmt.Sprintf("DNS 服务器 %q 改为新格式（%s）", tag, addr))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	dns["servers"] = kept
+	if legacyFakeIP != nil {
+		delete(dns, "fakeip")
+		changes = append(changes, "dns.fakeip 的地址范围移入 fakeip 服务器")
+	}
+
+	if len(rcodes) > 0 {
+		predefined := func(tag string) map[string]any {
+			if rcode, ok := rcodes[tag]; ok {
+				return map[string]any{"action": "predefined", "rcode": rcode}
+			}
+			return nil
+		}
+		rules, _ := dns["rules"].([]any)
+		rewriteRules(rules, "server", predefined)
+		if final, _ := dns["final"].(string); final != "" {
+			if fields := predefined(final); fields != nil {
+				delete(dns, "final")
+				dns["rules"] = append(rules, fields)
+				changes = append(changes, fmt.Sprintf("dns.final %q 改为末尾的 predefined 规则", final))
+			}
+		}
+	}
+	return changes, nil
+}
+
+// parseLegacyDNSAddress 解析旧写法的 DNS 服务器地址（rcode:// 由 migrateDNSServers 单独处理）。
+func parseLegacyDNSAddress(addr string) map[string]any {
+	switch addr {
+	case "local":
+		return map[string]any{"type": "local"}
+	case "fakeip":
+		return map[string]any{"type": "fakeip"}
+	}
+	typ, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		typ, rest = "udp", addr
+	}
+	if typ == "dhcp" {
+		out := map[string]any{"type": "dhcp"}
+		if rest != "" && rest != "auto" {
+			out["interface"] = rest
+		}
+		return out
+	}
+
+	out := map[string]any{"type": typ}
+	host, path, hasPath := strings.Cut(rest, "/")
+	if hasPath && (typ == "https" || typ == "h3") && path != "dns-query" {
+		out["path"] = "/" + path
+	}
+	if h, p, ok := cutPort(host); ok {
+		out["server"] = h
+		out["server_port"] = p
+	} else {
+		out["server"] = host
+	}
+	return out
+}
+
+func cutPort(hostport string) (string, int, bool) {
+	if strings.Count(hostport, ":") > 1 && !strings.HasPrefix(hostport, "[") {
+		return "", 0, false
+	}
+	i := strings.LastIndex(hostport, ":")
+	if i < 0 || strings.HasSuffix(hostport, "]") {
+		return "", 0, false
+	}
+	var port int
+	if _, err := fmt.Sscanf(hostport[i+1:], "%d", &port); err != nil {
+		return "", 0, false
+	}
+	return strings.Trim(hostport[:i], "[]"), port, true
+}
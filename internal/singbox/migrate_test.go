@@ -0,0 +1,197 @@
+package singbox
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		before string
+		after  string
+	}{
+		{
+			name:   "block/dns 出站改为规则动作，final 改为末尾规则",
+			target: "1.11.0",
+			before: `{
+				"outbounds": [{"type": "direct", "tag": "direct"}, {"type": "block", "tag": "block"}, {"type": "dns", "tag": "dns-out"}],
+				"route": {
+					"rules": [
+						{"protocol": "dns", "outbound": "dns-out"},
+						{"type": "logical", "mode": "or", "rules": [{"domain": ["a.com"], "outbound": "block"}, {"domain": ["b.com"]}], "outbound": "block"},
+						{"domain": ["c.com"], "outbound": "direct"}
+					],
+					"final": "block"
+				}
+			}`,
+			after: `{
+				"outbounds": [{"type": "direct", "tag": "direct"}],
+				"route": {
+					"rules": [
+						{"protocol": "dns", "action": "hijack-dns"},
+						{"type": "logical", "mode": "or", "rules": [{"domain": ["a.com"]}, {"domain": ["b.com"]}], "action": "reject"},
+						{"domain": ["c.com"], "outbound": "direct"},
+						{"action": "reject"}
+					]
+				}
+			}`,
+		},
+		{
+			name:   "final 指向普通出站时保留",
+			target: "1.11.0",
+			before: `{
+				"outbounds": [{"type": "direct", "tag": "direct"}, {"type": "block", "tag": "block"}],
+				"route": {"rules": [{"domain": ["a.com"], "outbound": "block"}], "final": "direct"}
+			}`,
+			after: `{
+				"outbounds": [{"type": "direct", "tag": "direct"}],
+				"route": {"rules": [{"domain": ["a.com"], "action": "reject"}], "final": "direct"}
+			}`,
+		},
+		{
+			name:   "入站旧字段改为规则动作",
+			target: "1.11.0",
+			before: `{
+				"inbounds": [{"type": "mixed", "tag": "in", "sniff": true, "sniff_timeout": "1s", "sniff_override_destination": false,
+					"domain_strategy": "prefer_ipv4", "udp_disable_domain_unmapping": true}],
+				"route": {"rules": [{"domain": ["x.com"], "outbound": "direct"}]}
+			}`,
+			after: `{
+				"inbounds": [{"type": "mixed", "tag": "in"}],
+				"route": {"rules": [
+					{"inbound": ["in"], "action": "sniff", "timeout": "1s"},
+					{"inbound": ["in"], "action": "resolve", "strategy": "prefer_ipv4"},
+					{"inbound": ["in"], "action": "route-options", "udp_disable_domain_unmapping": true},
+					{"domain": ["x.com"], "outbound": "direct"}
+				]}
+			}`,
+		},
+		{
+			name:   "sniff_override_destination 保留 sniff 旧字段",
+			target: "1.11.0",
+			before: `{
+				"inbounds": [{"type": "mixed", "tag": "in", "sniff": true, "sniff_timeout": "1s", "sniff_override_destination": true, "domain_strategy": "ipv4_only"}]
+			}`,
+			after: `{
+				"inbounds": [{"type": "mixed", "tag": "in", "sniff": true, "sniff_timeout": "1s", "sniff_override_destination": true}],
+				"route": {"rules": [{"inbound": ["in"], "action": "resolve", "strategy": "ipv4_only"}]}
+			}`,
+		},
+		{
+			name:   "DNS 服务器改为新格式，rcode 改为 predefined 动作",
+			target: "1.12.0",
+			before: `{
+				"dns": {
+					"servers": [
+						{"tag": "google", "address": "tls://8.8.8.8", "address_resolver": "local"},
+						{"tag": "local", "address": "local"},
+						{"tag": "block", "address": "rcode://name_error"},
+						{"tag": "fake", "address": "fakeip"}
+					],
+					"rules": [
+						{"domain": ["ads.com"], "server": "block"},
+						{"type": "logical", "mode": "and", "rules": [{"query_type": ["A"], "server": "block"}, {"domain_suffix": [".x"]}], "server": "block"},
+						{"query_type": ["A"], "server": "fake"}
+					],
+					"final": "block",
+					"fakeip": {"enabled": true, "inet4_range": "198.18.0.0/15", "inet6_range": "fc00::/18"}
+				}
+			}`,
+			after: `{
+				"dns": {
+					"servers": [
+						{"tag": "google", "type": "tls", "server": "8.8.8.8", "domain_resolver": "local"},
+						{"tag": "local", "type": "local"},
+						{"tag": "fake", "type": "fakeip", "inet4_range": "198.18.0.0/15", "inet6_range": "fc00::/18"}
+					],
+					"rules": [
+						{"domain": ["ads.com"], "action": "predefined", "rcode": "NXDOMAIN"},
+						{"type": "logical", "mode": "and", "rules": [{"query_type": ["A"]}, {"domain_suffix": [".x"]}], "action": "predefined", "rcode": "NXDOMAIN"},
+						{"query_type": ["A"], "server": "fake"},
+						{"action": "predefined", "rcode": "NXDOMAIN"}
+					]
+				}
+			}`,
+		},
+		{
+			name:   "rcode success 对应 NOERROR",
+			target: "1.12.0",
+			before: `{"dns": {"servers": [{"tag": "ok", "address": "rcode://success"}], "rules": [{"domain": ["a.com"], "server": "ok"}]}}`,
+			after:  `{"dns": {"servers": [], "rules": [{"domain": ["a.com"], "action": "predefined", "rcode": "NOERROR"}]}}`,
+		},
+		{
+			name:   "1.11 不迁移 DNS 服务器",
+			target: "1.11.5",
+			before: `{"dns": {"servers": [{"tag": "google", "address": "tls://8.8.8.8"}]}}`,
+			after:  `{"dns": {"servers": [{"tag": "google", "address": "tls://8.8.8.8"}]}}`,
+		},
+		{
+			name:   "1.10 不做任何迁移",
+			target: "1.10.7",
+			before: `{"outbounds": [{"type": "block", "tag": "block"}], "route": {"final": "block"}}`,
+			after:  `{"outbounds": [{"type": "block", "tag": "block"}], "route": {"final": "block"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes, err := MigrateConfig([]byte(tt.before), tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, got, []byte(tt.after)) {
+				t.Errorf("迁移结果:\n%s\n期望:\n%s", got, tt.after)
+			}
+			if changed := !jsonEqual(t, []byte(tt.before), []byte(tt.after)); changed != (len(changes) > 0) {
+				t.Errorf("changes = %q，与是否改动（%v）不符", changes, changed)
+			}
+		})
+	}
+}
+
+func TestMigrateConfigUnknownRcode(t *testing.T) {
+	raw := `{"dns": {"servers": [{"tag": "x", "address": "rcode://bogus"}]}}`
+	if _, _, err := MigrateConfig([]byte(raw), "1.12.0"); err == nil {
+		t.Fatal("未知的 rcode 应当报错")
+	}
+}
+
+func TestParseLegacyDNSAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want map[string]any
+	}{
+		{"local", map[string]any{"type": "local"}},
+		{"fakeip", map[string]any{"type": "fakeip"}},
+		{"8.8.8.8", map[string]any{"type": "udp", "server": "8.8.8.8"}},
+		{"2001:db8::1", map[string]any{"type": "udp", "server": "2001:db8::1"}},
+		{"tcp://1.1.1.1", map[string]any{"type": "tcp", "server": "1.1.1.1"}},
+		{"tls://1.1.1.1:853", map[string]any{"type": "tls", "server": "1.1.1.1", "server_port": 853}},
+		{"https://dns.google/dns-query", map[string]any{"type": "https", "server": "dns.google"}},
+		{"https://dns.example/custom", map[string]any{"type": "https", "server": "dns.example", "path": "/custom"}},
+		{"h3://[2606:4700::1111]:443/dns-query", map[string]any{"type": "h3", "server": "2606:4700::1111", "server_port": 443}},
+		{"quic://dns.adguard.com", map[string]any{"type": "quic", "server": "dns.adguard.com"}},
+		{"dhcp://auto", map[string]any{"type": "dhcp"}},
+		{"dhcp://eth0", map[string]any{"type": "dhcp", "interface": "eth0"}},
+	}
+	for _, tt := range tests {
+		if got := parseLegacyDNSAddress(tt.addr); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLegacyDNSAddress(%q) = %v，期望 %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
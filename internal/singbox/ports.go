@@ -0,0 +1,34 @@
+package singbox
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// ListenPorts 返回配置中所有入站的 listen_port（升序、去重）。
+func ListenPorts(configPath string) ([]int, error) {
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Inbounds []struct {
+			ListenPort int `json:"listen_port"`
+		} `json:"inbounds"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+
+	seen := map[int]bool{}
+	var ports []int
+	for _, in := range cfg.Inbounds {
+		if in.ListenPort > 0 && !seen[in.ListenPort] {
+			seen[in.ListenPort] = true
+			ports = append(ports, in.ListenPort)
+		}
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
@@ -10,11 +10,104 @@ import (
 	"strings"
 )
 
-func LatestVersion(ctx context.Context, httpClient *http.Client) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/repos/SagerNet/sing-box/releases/latest", nil)
+type Release struct {
+	Version    string
+	Prerelease bool
+	Assets     []Asset
+}
+
+type Asset struct {
+	Name   string
+	Size   int64
+	SHA256 string
+	URL    string
+}
+
+func (r Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+func LatestVersion(ctx context.Context, httpClient *http.Client, src Source, channel, cachePath string) (string, error) {
+	r, err := ResolveLatest(ctx, httpClient, src, channel, cachePath)
 	if err != nil {
 		return "", err
 	}
+	return r.Version, nil
+}
+
+func LatestRelease(ctx context.Context, httpClient *http.Client, src Source) (Release, error) {
+	return fetchRelease(ctx, httpClient, src.apiURL("/releases/latest"))
+}
+
+func FetchRelease(ctx context.Context, httpClient *http.Client, src Source, version string) (Release, error) {
+	return fetchRelease(ctx, httpClient, src.apiURL("/releases/tags/v"+strings.TrimPrefix(version, "v")))
+}
+
+func fetchRelease(ctx context.Context, httpClient *http.Client, url string) (Release, error) {
+	var payload releasePayload
+	if err := getJSON(ctx, httpClient, url, &payload); err != nil {
+		return Release{}, err
+	}
+	r := payload.release()
+	if r.Version == "" {
+		return Release{}, errors.New("获取版本信息失败：tag_name 为空")
+	}
+	return r, nil
+}
+
+// ListReleases 返回最近的 release（含预发布，不含草稿），按 API 顺序排列。
+func ListReleases(ctx context.Context, httpClient *http.Client, src Source) ([]Release, error) {
+	var payload []releasePayload
+	if err := getJSON(ctx, httpClient, src.apiURL("/releases?per_page=50"), &payload); err != nil {
+		return nil, err
+	}
+	var out []Release
+	for _, p := range payload {
+		if r := p.release(); !p.Draft && r.Version != "" {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+type releasePayload struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		Size               int64  `json:"size"`
+		Digest             string `json:"digest"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (p releasePayload) release() Release {
+	r := Release{
+		Version:    strings.TrimPrefix(strings.TrimSpace(p.TagName), "v"),
+		Prerelease: p.Prerelease,
+	}
+	for _, a := range p.Assets {
+		r.Assets = append(r.Assets, Asset{
+			Name:   a.Name,
+			Size:   a.Size,
+			SHA256: parseDigest(a.Digest),
+			URL:    a.BrowserDownloadURL,
+		})
+	}
+	return r
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("User-Agent", "alpine-vless-installer")
 	if tok := strings.TrimSpace(os.Getenv("GITHUB_TOKEN")); tok != "" {
@@ -23,33 +116,29 @@ func LatestVersion(ctx context.Context, httpClient *http.Client) (string, error)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", wrapHTTPDoError(err)
+		return wrapHTTPDoError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode/100 != 2 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
 		if resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0" {
-			return "", errors.New("GitHub API 限流：可设置环境变量 GITHUB_TOKEN（Personal Access Token）或稍后重试")
+			return errors.New("GitHub API 限流：可设置环境变量 GITHUB_TOKEN（Personal Access Token）或稍后重试")
 		}
 		msg := strings.TrimSpace(string(body))
 		if msg == "" {
 			msg = "无响应内容"
 		}
-		return "", errors.New("获取最新版本失败（GitHub API）：HTTP " + resp.Status + "：" + msg)
+		return errors.New("获取版本信息失败（GitHub API）：HTTP " + resp.Status + "：" + msg)
 	}
 
-	var payload struct {
-		TagName string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", err
-	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
 
-	v := strings.TrimSpace(payload.TagName)
-	v = strings.TrimPrefix(v, "v")
-	if v == "" {
-		return "", errors.New("获取最新版本失败：tag_name 为空")
+func parseDigest(d string) string {
+	algo, hexDigest, ok := strings.Cut(strings.TrimSpace(d), ":")
+	if !ok || !strings.EqualFold(algo, "sha256") {
+		return ""
 	}
-	return v, nil
+	return strings.ToLower(hexDigest)
 }
@@ -0,0 +1,127 @@
+package singbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const versionCacheTTL = 12 * time.Hour
+
+type versionCache struct {
+	Version    string    `json:"version"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// ResolveLatest 返回 channel 上的最新 release。
+// stable 通道依次尝试 GitHub API、releases/latest 跳转与本地缓存；其他通道需要 release 列表，失败时回退到缓存。
+// cachePath 为空时不使用缓存；仅当 API 成功时，返回的 Release 才带有 asset 列表。
+func ResolveLatest(ctx context.Context, httpClient *http.Client, src Source, channel, cachePath string) (Release, error) {
+	if channel != "" && channel != ChannelStable {
+		return resolveChannel(ctx, httpClient, src, channel, cachePath)
+	}
+
+	rel, apiErr := LatestRelease(ctx, httpClient, src)
+	if apiErr == nil {
+		saveVersionCache(cachePath, rel.Version)
+		return rel, nil
+	}
+
+	v, redirErr := latestVersionFromRedirect(ctx, httpClient, src)
+	if redirErr == nil {
+		saveVersionCache(cachePath, v)
+		return Release{Version: v}, nil
+	}
+
+	if v, ok := loadVersionCache(cachePath); ok {
+		return Release{Version: v}, nil
+	}
+	return Release{}, fmt.Errorf("%w；releases/latest 跳转解析也失败：%v", apiErr, redirErr)
+}
+
+func resolveChannel(ctx context.Context, httpClient *http.Client, src Source, channel, cachePath string) (Release, error) {
+	rels, err := ListReleases(ctx, httpClient, src)
+	if err == nil {
+		if rel, ok := pickChannel(rels, channel); ok {
+			saveVersionCache(cachePath, rel.Version)
+			return rel, nil
+		}
+		err = fmt.Errorf("通道 %s 上没有可用的 release", channel)
+	}
+	if v, ok := loadVersionCache(cachePath); ok {
+		return Release{Version: v}, nil
+	}
+	return Release{}, err
+}
+
+func latestVersionFromRedirect(ctx context.Context, httpClient *http.Client, src Source) (string, error) {
+	url := strings.TrimSuffix(strings.TrimRight(src.DownloadBaseURL, "/"), "/download") + "/latest"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "alpine-vless-installer")
+
+	c := *httpClient
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", wrapHTTPDoError(err)
+	}
+	resp.Body.Close()
+
+	loc := resp.Header.Get("Location")
+	if resp.StatusCode/100 != 3 || loc == "" {
+		return "", fmt.Errorf("%s 未返回跳转（HTTP %d）", url, resp.StatusCode)
+	}
+	if i := strings.IndexAny(loc, "?#"); i >= 0 {
+		loc = loc[:i]
+	}
+	if path.Base(path.Dir(loc)) != "tag" {
+		return "", fmt.Errorf("无法从跳转地址解析版本: %s", loc)
+	}
+	v := strings.TrimPrefix(path.Base(loc), "v")
+	if v == "" || v == "." {
+		return "", errors.New("跳转地址中的版本为空")
+	}
+	return v, nil
+}
+
+func loadVersionCache(cachePath string) (string, bool) {
+	if cachePath == "" {
+		return "", false
+	}
+	b, err := os.ReadFile(cachePath)
+	if err != nil {
+		return "", false
+	}
+	var c versionCache
+	if err := json.Unmarshal(b, &c); err != nil || c.Version == "" {
+		return "", false
+	}
+	if time.Since(c.ResolvedAt) > versionCacheTTL {
+		return "", false
+	}
+	return c.Version, true
+}
+
+func saveVersionCache(cachePath, version string) {
+	if cachePath == "" {
+		return
+	}
+	b, err := json.Marshal(versionCache{Version: version, ResolvedAt: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(cachePath, append(b, '\n'), 0600)
+}
@@ -0,0 +1,57 @@
+package singbox
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+var hostMachines = map[string]elf.Machine{
+	"amd64":    elf.EM_X86_64,
+	"386":      elf.EM_386,
+	"arm64":    elf.EM_AARCH64,
+	"arm":      elf.EM_ARM,
+	"s390x":    elf.EM_S390,
+	"riscv64":  elf.EM_RISCV,
+	"ppc64le":  elf.EM_PPC64,
+	"loong64":  elf.EM_LOONGARCH,
+	"mips64le": elf.EM_MIPS,
+	"mipsle":   elf.EM_MIPS,
+}
+
+// checkBinary 在替换前确认新解压的 sing-box 与本机架构一致且能正常运行；
+// wantVersion 非空时还要求 `sing-box version` 报告的版本一致。
+func checkBinary(ctx context.Context, path, wantVersion string) (BinaryInfo, error) {
+	if err := checkELFMachine(path); err != nil {
+		return BinaryInfo{}, err
+	}
+
+	info, err := BinaryVersion(ctx, path)
+	if err != nil {
+		return BinaryInfo{}, fmt.Errorf("新 sing-box 无法运行: %w", err)
+	}
+	if wantVersion != "" && info.Version != wantVersion {
+		return info, fmt.Errorf("新 sing-box 报告版本 %s（构建标签: %s），与期望的 %s 不一致",
+			info.Version, strings.Join(info.Tags, ","), wantVersion)
+	}
+	return info, nil
+}
+
+func checkELFMachine(path string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return fmt.Errorf("新 sing-box 不是有效的 ELF 可执行文件（可能下载不完整）: %w", err)
+	}
+	defer f.Close()
+
+	want, ok := hostMachines[runtime.GOARCH]
+	if !ok {
+		return nil
+	}
+	if f.Machine != want {
+		return fmt.Errorf("新 sing-box 的 ELF 机器类型为 %s，与本机 %s（%s）不匹配", f.Machine, want, runtime.GOARCH)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package singbox
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	defaultAPIBaseURL      = "https://api.github.com/repos/SagerNet/sing-box"
+	defaultDownloadBaseURL = "https://github.com/SagerNet/sing-box/releases/download"
+)
+
+// Source 描述 release 元数据与下载地址，便于使用镜像或 GitHub 代理前缀。
+type Source struct {
+	APIBaseURL      string
+	DownloadBaseURL string
+}
+
+func SourceFromEnv() Source {
+	s := Source{
+		APIBaseURL:      defaultAPIBaseURL,
+		DownloadBaseURL: defaultDownloadBaseURL,
+	}
+	if v := strings.TrimSpace(os.Getenv("ALPINE_VLESS_API_URL")); v != "" {
+		s.APIBaseURL = v
+	}
+	if v := strings.TrimSpace(os.Getenv("ALPINE_VLESS_DOWNLOAD_URL")); v != "" {
+		s.DownloadBaseURL = v
+	}
+	return s
+}
+
+func (s Source) apiURL(path string) string {
+	return strings.TrimRight(s.APIBaseURL, "/") + path
+}
+
+func (s Source) downloadURL(version, asset string) string {
+	return strings.TrimRight(s.DownloadBaseURL, "/") + "/v" + version + "/" + asset
+}
@@ -0,0 +1,105 @@
+package singbox
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+type BinaryInfo struct {
+	Version   string
+	Tags      []string
+	GoVersion string
+	Revision  string
+}
+
+// BinaryVersion 运行 `<path> version` 并解析版本号与构建标签。
+func BinaryVersion(ctx context.Context, path string) (BinaryInfo, error) {
+	out, err := system.Output(ctx, path, "version")
+	if err != nil {
+		return BinaryInfo{}, err
+	}
+	return parseVersionOutput(out)
+}
+
+func parseVersionOutput(out string) (BinaryInfo, error) {
+	var info BinaryInfo
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "sing-box version "):
+			info.Version = strings.TrimSpace(strings.TrimPrefix(line, "sing-box version "))
+		case strings.HasPrefix(line, "Tags:"):
+			for _, t := range strings.Split(strings.TrimPrefix(line, "Tags:"), ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					info.Tags = append(info.Tags, t)
+				}
+			}
+		case strings.HasPrefix(line, "Environment:"):
+			fields := strings.Fields(strings.TrimPrefix(line, "Environment:"))
+			if len(fields) > 0 {
+				info.GoVersion = fields[0]
+			}
+		case strings.HasPrefix(line, "Revision:"):
+			info.Revision = strings.TrimSpace(strings.TrimPrefix(line, "Revision:"))
+		}
+	}
+	if info.Version == "" {
+		return BinaryInfo{}, errors.New("无法解析 sing-box version 输出")
+	}
+	return info, nil
+}
+
+// CompareVersions 比较两个 sing-box 版本号（如 1.10.1、1.11.0-beta.3），返回 -1/0/1。
+func CompareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+
+	if c := compareDotted(aCore, bCore); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return compareDotted(aPre, bPre)
+}
+
+func compareDotted(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case x == y:
+			continue
+		case xerr == nil && yerr == nil:
+			if xn < yn {
+				return -1
+			}
+			if xn > yn {
+				return 1
+			}
+		case x < y:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return 0
+}
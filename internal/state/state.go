@@ -0,0 +1,125 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+type State struct {
+	SingBox    Binary     `json:"sing_box"`
+	Channel    string     `json:"channel,omitempty"`
+	AutoUpdate AutoUpdate `json:"auto_update"`
+	Service    Service    `json:"service"`
+	LogRotate  LogRotate  `json:"log_rotate"`
+
+	// Source 为 sing-box 安装来源（github/apk），为空视为 github。
+	Source string `json:"source,omitempty"`
+	// PackageOwned 表示 apk 包由本工具安装，卸载时一并 apk del；已有的包不会被删除。
+	PackageOwned bool `json:"package_owned,omitempty"`
+	// CreatedServiceUser 为本工具创建的服务用户名，卸载或改用其他用户时删除；已有的用户不会被删除。
+	CreatedServiceUser string `json:"created_service_user,omitempty"`
+	// ServiceUserCreated 为旧版本的记录方式，Load 时迁移到 CreatedServiceUser。
+	ServiceUserCreated bool `json:"service_user_created,omitempty"`
+}
+
+type AutoUpdate struct {
+	Enabled bool `json:"enabled"`
+	// Window 为维护窗口（本地时间），格式 HH:MM-HH:MM，可跨越午夜。
+	Window string `json:"window,omitempty"`
+}
+
+// LogRotate 为数据目录日志的轮转设置。
+type LogRotate struct {
+	Enabled bool `json:"enabled"`
+	// Backend 为 builtin（定时任务运行 log-rotate run）或 logrotate（写入 /etc/logrotate.d）。
+	Backend string `json:"backend,omitempty"`
+	MaxSize int64  `json:"max_size"`
+	// Interval 为 daily/weekly/monthly，为空只按大小轮转。
+	Interval string `json:"interval,omitempty"`
+	Keep     int    `json:"keep"`
+	// LastRotated 记录 builtin 方式下各文件上次轮转的时间。
+	LastRotated map[string]time.Time `json:"last_rotated,omitempty"`
+}
+
+// Service 为生成服务定义（OpenRC conf.d 或 systemd unit）的参数，sing-box 崩溃后按此自动重启。
+type Service struct {
+	// Configured 表示参数由用户显式设置过，此时全零的参数同样有效。
+	Configured bool `json:"configured,omitempty"`
+
+	// RespawnDelay 为重启前等待的秒数。
+	RespawnDelay int `json:"respawn_delay"`
+	// RespawnMax 为 RespawnPeriod 秒内允许的最大重启次数，0 表示不限制。
+	RespawnMax    int `json:"respawn_max"`
+	RespawnPeriod int `json:"respawn_period"`
+
+	// User 为运行 sing-box 的系统用户，为空表示 root。
+	User string `json:"user,omitempty"`
+	// Group 为 User 的主组名，写入服务定义前解析，不保存。
+	Group string `json:"-"`
+
+	Limits Limits `json:"limits"`
+}
+
+// Limits 为资源限制与 Go 运行时参数；除 Nice/IONice 外，零值表示按本机内存自动选择。
+type Limits struct {
+	// NoFile 为最大打开文件数（rc_ulimit -n）。
+	NoFile int `json:"nofile,omitempty"`
+	// MemLimit 为 GOMEMLIMIT，如 150M；off 表示不限制。
+	MemLimit string `json:"gomemlimit,omitempty"`
+	// GOGC 为整数百分比或 off。
+	GOGC string `json:"gogc,omitempty"`
+	// Nice 为调度优先级（-20..19），0 表示不调整。
+	Nice int `json:"nice,omitempty"`
+	// IONice 为 I/O 优先级 class[:level]（如 2:7），为空表示不调整。
+	IONice string `json:"ionice,omitempty"`
+	// OOMScoreAdj 为 oom_score_adj（-1000..1000），越小越不容易被 OOM killer 选中。
+	OOMScoreAdj string `json:"oom_score_adj,omitempty"`
+}
+
+type Binary struct {
+	Version     string    `json:"version"`
+	Arch        string    `json:"arch"`
+	Asset       string    `json:"asset"`
+	Tags        []string  `json:"tags,omitempty"`
+	SHA256      string    `json:"sha256"`
+	Verified    bool      `json:"verified"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+func Load(path string) (State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return State{}, nil
+		}
+		return State{}, err
+	}
+
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return State{}, err
+	}
+	if s.ServiceUserCreated {
+		if s.CreatedServiceUser == "" {
+			s.CreatedServiceUser = s.Service.User
+		}
+		s.ServiceUserCreated = false
+	}
+	return s, nil
+}
+
+func Save(path string, s State) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,42 @@
+package system
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseBytes 解析带 K/M/G 后缀（1024 进制，可带 B/iB）的大小，如 "10M"、"512KiB"。
+func ParseBytes(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "B"), "I")
+	mult := int64(1)
+	if t != "" {
+		if i := strings.IndexByte("KMG", t[len(t)-1]); i >= 0 {
+			mult = int64(1) << (10 * (i + 1))
+			t = t[:len(t)-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("非法的大小 %q：示例 10M、512K", s)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("大小 %q 超出范围", s)
+	}
+	return n * mult, nil
+}
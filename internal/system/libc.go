@@ -0,0 +1,11 @@
+package system
+
+import "path/filepath"
+
+// Libc 返回本机 C 库类型："musl" 或 "glibc"。
+func Libc() string {
+	if m, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(m) > 0 {
+		return "musl"
+	}
+	return "glibc"
+}
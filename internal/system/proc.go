@@ -0,0 +1,213 @@
+package system
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// capBits 为 capability 名称对应的位（见 linux/capability.h）。
+var capBits = map[string]uint{
+	"cap_net_bind_service": 10,
+	"cap_net_admin":        12,
+}
+
+// CapabilityMask 返回一组 capability 名称对应的位掩码，未知名称返回错误。
+func CapabilityMask(names []string) (uint64, error) {
+	var mask uint64
+	for _, n := range names {
+		bit, ok := capBits[n]
+		if !ok {
+			return 0, fmt.Errorf("未知的 capability: %s", n)
+		}
+		mask |= 1 << bit
+	}
+	return mask, nil
+}
+
+// ProcStatus 为 /proc/<pid>/status 中关心的字段。
+type ProcStatus struct {
+	// UIDs 依次为 real、effective、saved、filesystem UID。
+	UIDs   []int
+	GIDs   []int
+	CapEff uint64
+	// VmRSS 为常驻内存（字节）。
+	VmRSS int64
+}
+
+func ReadProcStatus(pid int) (ProcStatus, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return ProcStatus{}, err
+	}
+	defer f.Close()
+
+	var st ProcStatus
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, val, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch key {
+		case "Uid":
+			st.UIDs, err = parseInts(val)
+		case "Gid":
+			st.GIDs, err = parseInts(val)
+		case "CapEff":
+			st.CapEff, err = strconv.ParseUint(val, 16, 64)
+		case "VmRSS":
+			var kb int64
+			kb, err = strconv.ParseInt(strings.TrimSuffix(val, " kB"), 10, 64)
+			st.VmRSS = kb * 1024
+		}
+		if err != nil {
+			return ProcStatus{}, fmt.Errorf("解析 /proc/%d/status 的 %s 失败: %w", pid, key, err)
+		}
+	}
+	return st, sc.Err()
+}
+
+// MemTotal 返回 /proc/meminfo 中的物理内存总量（字节）。
+func MemTotal() (int64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		val, ok := strings.CutPrefix(sc.Text(), "MemTotal:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(val), " kB"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("解析 /proc/meminfo 的 MemTotal 失败: %w", err)
+		}
+		return kb * 1024, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("/proc/meminfo 中没有 MemTotal")
+}
+
+// clockTicks 为 /proc/<pid>/stat 中时间字段的单位（USER_HZ，Linux 上固定为 100）。
+const clockTicks = 100
+
+// ProcUptime 返回进程已运行的时长。
+func ProcUptime(pid int) (time.Duration, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// comm 字段可能包含空格，从最后一个 ')' 之后开始按空格切分；starttime 为第 22 个字段。
+	i := strings.LastIndexByte(string(b), ')')
+	if i < 0 {
+		return 0, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	u, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	up, err := strconv.ParseFloat(strings.Fields(string(u))[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(up*float64(time.Second)) - time.Duration(start)*time.Second/clockTicks
+	return d.Truncate(time.Second), nil
+}
+
+// OpenFDs 返回进程打开的文件描述符数量。
+func OpenFDs(pid int) (int, error) {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// HasOpenFile 判断进程是否仍打开着 path（按 /proc/<pid>/fd 的链接目标比较）。
+func HasOpenFile(pid int, path string) (bool, error) {
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		if target, err := os.Readlink(dir + "/" + e.Name()); err == nil && target == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tcpListen 为 /proc/net/tcp 中 LISTEN 状态的编码。
+const tcpListen = "0A"
+
+// TCPListening 根据 /proc/net/tcp 与 /proc/net/tcp6 判断是否有套接字在监听 port。
+func TCPListening(port int) (bool, error) {
+	want := fmt.Sprintf(":%04X", port)
+	found := false
+	for _, name := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return false, err
+		}
+		found = true
+
+		sc := bufio.NewScanner(f)
+		sc.Scan() // 表头
+		for sc.Scan() {
+			// sl local_address rem_address st ...
+			fields := strings.Fields(sc.Text())
+			if len(fields) < 4 {
+				continue
+			}
+			if strings.HasSuffix(fields[1], want) && fields[3] == tcpListen {
+				_ = f.Close()
+				return true, nil
+			}
+		}
+		err = sc.Err()
+		_ = f.Close()
+		if err != nil {
+			return false, err
+		}
+	}
+	if !found {
+		return false, errors.New("未找到 /proc/net/tcp")
+	}
+	return false, nil
+}
+
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Fields(s) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
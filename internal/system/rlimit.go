@@ -0,0 +1,30 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// SetNoFile 把当前进程的最大打开文件数（软、硬限制）设为 n，之后启动的子进程继承该限制；
+// 无权提高硬限制时把软限制提高到硬限制并返回错误。
+func SetNoFile(n uint64) error {
+	var cur syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &cur); err != nil {
+		return err
+	}
+	err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &syscall.Rlimit{Cur: n, Max: n})
+	if err == nil || n <= cur.Max {
+		return err
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &syscall.Rlimit{Cur: cur.Max, Max: cur.Max}); err != nil {
+		return err
+	}
+	return fmt.Errorf("硬限制为 %d，无法提高到 %d: %w", cur.Max, n, err)
+}
+
+// SetOOMScoreAdj 写入进程的 /proc/<pid>/oom_score_adj；调低需要 CAP_SYS_RESOURCE。
+func SetOOMScoreAdj(pid int, v string) error {
+	return os.WriteFile("/proc/"+strconv.Itoa(pid)+"/oom_score_adj", []byte(v), 0644)
+}
@@ -19,3 +19,12 @@ func Run(ctx context.Context, name string, args ...string) error {
 	}
 	return nil
 }
+
+func Output(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %v 失败: %w: %s", name, args, err, string(out))
+	}
+	return string(out), nil
+}
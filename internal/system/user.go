@@ -0,0 +1,101 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"os/user"
+	"strconv"
+)
+
+// SystemUser 描述一个系统用户及其主组。
+type SystemUser struct {
+	Name  string
+	UID   int
+	GID   int
+	Group string
+}
+
+func LookupUser(name string) (SystemUser, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return SystemUser{}, err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return SystemUser{}, err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return SystemUser{}, err
+	}
+	g, err := user.LookupGroupId(u.Gid)
+	if err != nil {
+		return SystemUser{}, err
+	}
+	return SystemUser{Name: name, UID: uid, GID: gid, Group: g.Name}, nil
+}
+
+// EnsureSystemUser 创建无登录 shell、无家目录的系统用户与同名组（有 useradd 时使用 shadow 工具，
+// 否则使用 busybox addgroup/adduser），已存在时直接返回；created 表示本次新建。
+func EnsureSystemUser(ctx context.Context, name string) (u SystemUser, created bool, err error) {
+	if u, err := LookupUser(name); err == nil {
+		return u, false, nil
+	} else if !errors.As(err, new(user.UnknownUserError)) {
+		return SystemUser{}, false, err
+	}
+
+	if CommandExists("useradd") {
+		args := []string{"--system", "--no-create-home", "--home-dir", "/nonexistent", "--shell", nologinShell()}
+		if _, err := user.LookupGroup(name); err == nil {
+			args = append(args, "--gid", name)
+		} else {
+			args = append(args, "--user-group")
+		}
+		if err := Run(ctx, "useradd", append(args, name)...); err != nil {
+			return SystemUser{}, false, err
+		}
+		u, err = LookupUser(name)
+		return u, err == nil, err
+	}
+
+	if _, err := user.LookupGroup(name); err != nil {
+		if err := Run(ctx, "addgroup", "-S", name); err != nil {
+			return SystemUser{}, false, err
+		}
+	}
+	if err := Run(ctx, "adduser", "-S", "-D", "-H", "-h", "/dev/null", "-s", "/sbin/nologin", "-G", name, name); err != nil {
+		return SystemUser{}, false, err
+	}
+	u, err = LookupUser(name)
+	return u, err == nil, err
+}
+
+// nologinShell 返回禁止登录的 shell：Debian 等发行版位于 /usr/sbin/nologin。
+func nologinShell() string {
+	for _, p := range []string{"/usr/sbin/nologin", "/sbin/nologin"} {
+		if FileExists(p) {
+			return p
+		}
+	}
+	return "/bin/false"
+}
+
+// DeleteSystemUser 删除系统用户及其同名组。
+func DeleteSystemUser(ctx context.Context, name string) error {
+	if CommandExists("userdel") {
+		if err := Run(ctx, "userdel", name); err != nil {
+			return err
+		}
+		if _, err := user.LookupGroup(name); err == nil {
+			return Run(ctx, "groupdel", name)
+		}
+		return nil
+	}
+	if err := Run(ctx, "deluser", name); err != nil {
+		return err
+	}
+	if _, err := user.LookupGroup(name); err == nil {
+		return Run(ctx, "delgroup", name)
+	}
+	return nil
+}
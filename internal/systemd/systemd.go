@@ -0,0 +1,183 @@
+package systemd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/initsys"
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/state"
+	"github.com/pkssssss/alpine-vless/internal/system"
+)
+
+const unitDir = "/etc/systemd/system"
+
+// System 为 systemd 后端：每个实例一个 /etc/systemd/system/<服务名>.service。
+type System struct{}
+
+var _ initsys.System = System{}
+
+func (System) Name() string { return "systemd" }
+
+// Available 判断 systemd 是否作为 init 运行（与 sd_booted 的判断一致）。
+func Available() bool {
+	fi, err := os.Stat("/run/systemd/system")
+	return err == nil && fi.IsDir() && system.CommandExists("systemctl")
+}
+
+func unitFile(serviceName string) string {
+	return filepath.Join(unitDir, serviceName+".service")
+}
+
+func isManagedFile(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(b, []byte(initsys.ManagedMarker))
+}
+
+func (System) IsInstalled(p paths.Paths) bool {
+	return isManagedFile(unitFile(p.ServiceName))
+}
+
+func (System) ServiceFile(p paths.Paths) string {
+	return unitFile(p.ServiceName)
+}
+
+// Install 写入 unit 文件并让 systemd 重新加载。
+func (System) Install(ctx context.Context, p paths.Paths, svc state.Service, caps []string) error {
+	f := unitFile(p.ServiceName)
+	if system.FileExists(f) && !isManagedFile(f) {
+		return fmt.Errorf("检测到已有 unit 文件 %s，但不是本工具管理，拒绝覆盖", f)
+	}
+	if err := system.MkdirAll0755(unitDir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(f, []byte(Unit(p, svc, caps)), 0644); err != nil {
+		return err
+	}
+	return system.Run(ctx, "systemctl", "daemon-reload")
+}
+
+func (System) EnableAndStart(ctx context.Context, serviceName string) error {
+	if err := system.Run(ctx, "systemctl", "enable", serviceName); err != nil {
+		return err
+	}
+	return system.Run(ctx, "systemctl", "restart", serviceName)
+}
+
+func (System) Restart(ctx context.Context, serviceName string) error {
+	return system.Run(ctx, "systemctl", "restart", serviceName)
+}
+
+func (System) Reload(ctx context.Context, serviceName string) error {
+	return system.Run(ctx, "systemctl", "reload", serviceName)
+}
+
+// Remove 停止、禁用并删除实例的 unit 文件。
+func (System) Remove(ctx context.Context, p paths.Paths) error {
+	f := unitFile(p.ServiceName)
+	if system.FileExists(f) && !isManagedFile(f) {
+		return errors.New("检测到非本工具管理的 systemd unit 文件，拒绝卸载")
+	}
+	_ = system.Run(ctx, "systemctl", "disable", "--now", p.ServiceName)
+	_ = os.Remove(f)
+	_ = system.Run(ctx, "systemctl", "daemon-reload")
+	_ = system.Run(ctx, "systemctl", "reset-failed", p.ServiceName)
+	return nil
+}
+
+// Instances 通过 /etc/systemd/system 中带管理标记的 unit 列出本机的所有实例。
+func (System) Instances() ([]initsys.Instance, error) {
+	matches, err := filepath.Glob(filepath.Join(unitDir, paths.BaseServiceName+"*.service"))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []initsys.Instance
+	for _, f := range matches {
+		name := strings.TrimSuffix(filepath.Base(f), ".service")
+		inst, _ := strings.CutPrefix(name, paths.BaseServiceName)
+		if inst != "" && !strings.HasPrefix(inst, ".") {
+			continue
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(b, []byte(initsys.ManagedMarker)) {
+			continue
+		}
+		home := ""
+		sc := bufio.NewScanner(bytes.NewReader(b))
+		for sc.Scan() {
+			if v, ok := strings.CutPrefix(sc.Text(), homeKey); ok {
+				home = v
+				break
+			}
+		}
+		out = append(out, initsys.Instance{Name: strings.TrimPrefix(inst, "."), ServiceName: name, Home: home})
+	}
+	return out, nil
+}
+
+// show 返回 systemctl show 的单个属性值。
+func show(ctx context.Context, serviceName, prop string) (string, error) {
+	out, err := system.Output(ctx, "systemctl", "show", "-p", prop, "--value", serviceName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Status 返回 ActiveState 与 SubState，如 active (running)、failed (failed)。
+func (System) Status(ctx context.Context, serviceName string) (string, bool, error) {
+	active, err := show(ctx, serviceName, "ActiveState")
+	if err != nil {
+		return "", false, err
+	}
+	sub, err := show(ctx, serviceName, "SubState")
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("%s (%s)", active, sub), active == "active", nil
+}
+
+func (System) Enabled(ctx context.Context, serviceName string) bool {
+	v, err := show(ctx, serviceName, "UnitFileState")
+	return err == nil && v == "enabled"
+}
+
+func (System) MainPID(ctx context.Context, serviceName string) (int, error) {
+	v, err := show(ctx, serviceName, "MainPID")
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(v)
+	if err != nil || pid == 0 {
+		return 0, fmt.Errorf("无法确定 %s 的进程号：服务未运行", serviceName)
+	}
+	return pid, nil
+}
+
+func (System) MainPIDShell(serviceName string) string {
+	return fmt.Sprintf("systemctl show -p MainPID --value %s", serviceName)
+}
+
+// RespawnCount 返回 systemd 记录的自动重启次数（NRestarts，systemd 235+）。
+func (System) RespawnCount(ctx context.Context, serviceName string) (int, bool) {
+	v, err := show(ctx, serviceName, "NRestarts")
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil
+}
@@ -0,0 +1,82 @@
+package systemd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkssssss/alpine-vless/internal/initsys"
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/state"
+)
+
+// homeKey 记录实例的数据目录（写在 unit 的注释中），供 Instances 列出。
+const homeKey = "# alpine_vless_home="
+
+// ioClasses 为 ionice 的 class 编号对应的 IOSchedulingClass；0（none）不设置。
+var ioClasses = map[string]string{"1": "realtime", "2": "best-effort", "3": "idle"}
+
+// Unit 生成实例的 unit 文件内容，与 OpenRC 的 init 脚本 + conf.d 等价：崩溃后按 svc 的 respawn 参数重启，
+// stdout/stderr 追加到数据目录，并应用 svc.User、caps 与 svc.Limits。
+func Unit(p paths.Paths, svc state.Service, caps []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s%s\n", initsys.ManagedMarker, homeKey, p.RootDir)
+
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=sing-box VLESS Reality (%s)\n", p.ServiceName)
+	b.WriteString("Wants=network-online.target\nAfter=network-online.target\n")
+	if svc.RespawnMax > 0 {
+		fmt.Fprintf(&b, "StartLimitIntervalSec=%d\n", svc.RespawnPeriod)
+		fmt.Fprintf(&b, "StartLimitBurst=%d\n", svc.RespawnMax)
+	} else {
+		b.WriteString("StartLimitIntervalSec=0\n")
+	}
+
+	b.WriteString("\n[Service]\nType=simple\n")
+	fmt.Fprintf(&b, "ExecStart=%s run -c %s\n", quote(p.SingBoxPath), quote(p.ConfigPath))
+	b.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+	b.WriteString("Restart=always\n")
+	fmt.Fprintf(&b, "RestartSec=%d\n", svc.RespawnDelay)
+	if svc.User != "" {
+		fmt.Fprintf(&b, "User=%s\n", svc.User)
+		if svc.Group != "" {
+			fmt.Fprintf(&b, "Group=%s\n", svc.Group)
+		}
+		set := strings.ToUpper(strings.Join(caps, " "))
+		fmt.Fprintf(&b, "AmbientCapabilities=%s\n", set)
+		fmt.Fprintf(&b, "CapabilityBoundingSet=%s\n", set)
+		b.WriteString("NoNewPrivileges=true\n")
+	}
+	fmt.Fprintf(&b, "StandardOutput=append:%s\n", p.OpenRCOutLogPath)
+	fmt.Fprintf(&b, "StandardError=append:%s\n", p.OpenRCErrLogPath)
+
+	l := svc.Limits
+	if l.NoFile > 0 {
+		fmt.Fprintf(&b, "LimitNOFILE=%d\n", l.NoFile)
+	}
+	if l.MemLimit != "" {
+		fmt.Fprintf(&b, "Environment=GOMEMLIMIT=%s\n", l.MemLimit)
+	}
+	if l.GOGC != "" {
+		fmt.Fprintf(&b, "Environment=GOGC=%s\n", l.GOGC)
+	}
+	if l.Nice != 0 {
+		fmt.Fprintf(&b, "Nice=%d\n", l.Nice)
+	}
+	if class, level, ok := strings.Cut(l.IONice, ":"); ioClasses[class] != "" {
+		fmt.Fprintf(&b, "IOSchedulingClass=%s\n", ioClasses[class])
+		if ok {
+			fmt.Fprintf(&b, "IOSchedulingPriority=%s\n", level)
+		}
+	}
+	if l.OOMScoreAdj != "" {
+		fmt.Fprintf(&b, "OOMScoreAdjust=%s\n", l.OOMScoreAdj)
+	}
+
+	b.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// quote 按 systemd 的命令行规则为参数加双引号。
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
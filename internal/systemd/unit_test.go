@@ -0,0 +1,116 @@
+package systemd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkssssss/alpine-vless/internal/paths"
+	"github.com/pkssssss/alpine-vless/internal/state"
+)
+
+func TestUnit(t *testing.T) {
+	p := paths.At("/var/lib/alpine-vless", "")
+	tests := []struct {
+		name   string
+		p      paths.Paths
+		svc    state.Service
+		caps   []string
+		want   []string
+		absent []string
+	}{
+		{
+			name: "root 且不限制重启次数",
+			p:    p,
+			svc:  state.Service{RespawnDelay: 2},
+			want: []string{
+				"StartLimitIntervalSec=0\n",
+				"RestartSec=2\n",
+				`ExecStart="/var/lib/alpine-vless/sing-box" run -c "/var/lib/alpine-vless/config.json"` + "\n",
+			},
+			absent: []string{"StartLimitBurst=", "User=", "Group=", "AmbientCapabilities=", "NoNewPrivileges=", "Environment=", "LimitNOFILE=", "IOScheduling"},
+		},
+		{
+			name: "服务用户与 capability",
+			p:    p,
+			svc:  state.Service{RespawnDelay: 2, RespawnMax: 10, RespawnPeriod: 60, User: "sing-box", Group: "proxy"},
+			caps: []string{"cap_net_bind_service", "cap_net_admin"},
+			want: []string{
+				"StartLimitIntervalSec=60\n",
+				"StartLimitBurst=10\n",
+				"User=sing-box\nGroup=proxy\n",
+				"AmbientCapabilities=CAP_NET_BIND_SERVICE CAP_NET_ADMIN\n",
+				"CapabilityBoundingSet=CAP_NET_BIND_SERVICE CAP_NET_ADMIN\n",
+				"NoNewPrivileges=true\n",
+			},
+			absent: []string{"StartLimitIntervalSec=0\n"},
+		},
+		{
+			name: "服务用户不需要 capability",
+			p:    p,
+			svc:  state.Service{User: "sing-box", Group: "sing-box"},
+			want: []string{
+				"User=sing-box\nGroup=sing-box\n",
+				"AmbientCapabilities=\n",
+				"CapabilityBoundingSet=\n",
+			},
+		},
+		{
+			name: "资源限制",
+			p:    p,
+			svc: state.Service{Limits: state.Limits{
+				NoFile:      65535,
+				MemLimit:    "150MiB",
+				GOGC:        "50",
+				Nice:        5,
+				IONice:      "2:7",
+				OOMScoreAdj: "-500",
+			}},
+			want: []string{
+				"LimitNOFILE=65535\n",
+				"Environment=GOMEMLIMIT=150MiB\n",
+				"Environment=GOGC=50\n",
+				"Nice=5\n",
+				"IOSchedulingClass=best-effort\nIOSchedulingPriority=7\n",
+				"OOMScoreAdjust=-500\n",
+			},
+		},
+		{
+			name:   "ionice 只指定 class",
+			p:      p,
+			svc:    state.Service{Limits: state.Limits{IONice: "3"}},
+			want:   []string{"IOSchedulingClass=idle\n"},
+			absent: []string{"IOSchedulingPriority="},
+		},
+		{
+			name:   "ionice class 0 不设置",
+			p:      p,
+			svc:    state.Service{Limits: state.Limits{IONice: "0"}},
+			absent: []string{"IOScheduling"},
+		},
+		{
+			name: "路径需要转义",
+			p:    paths.At(`/srv/my "vless"\data`, "hk"),
+			want: []string{
+				`ExecStart="/srv/my \"vless\"\\data/sing-box" run -c "/srv/my \"vless\"\\data/config.json"` + "\n",
+				"Description=sing-box VLESS Reality (alpine-vless.hk)\n",
+				homeKey + `/srv/my "vless"\data` + "\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unit(tt.p, tt.svc, tt.caps)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("缺少 %q，生成的 unit:\n%s", w, got)
+				}
+			}
+			for _, a := range tt.absent {
+				if strings.Contains(got, a) {
+					t.Errorf("不应包含 %q，生成的 unit:\n%s", a, got)
+				}
+			}
+		})
+	}
+}